@@ -1,18 +1,72 @@
 package appconst
 
+import "time"
+
 const (
 	VideoMaxConcurrentResolutionParse = 3
 	VideoMaxConcurrentHLSProcesses    = 1
 	UnprecessedVideoDir               = "unprocessed_video"
+	DefaultJobStorePath               = "jobs.db"
+	DefaultKeyStoreDir                = "keys"
+	DefaultHLSKeyURITemplate          = "/keys/{video_id}/{key_id}"
+
+	// Finished jobs are dropped from the job tracker after JobTrackerRetention, their status
+	// is still served from the job store
+	JobTrackerRetention     = time.Hour
+	JobTrackerSweepInterval = 5 * time.Minute
 )
 
 const (
-	TopicVideoProcessed   = "video_processed"
-	TopicNewVideoUploaded = "new_video_uploaded"
+	TopicVideoProcessed        = "video_processed"
+	TopicNewVideoUploaded      = "new_video_uploaded"
+	TopicVideoProcessingFailed = "video_processing_failed"
+	TopicVideoSegmentsUploaded = "video_segments_uploaded"
+)
+
+const (
+	RetryDefaultMaxAttempts    = 3
+	RetryDefaultInitialBackoff = 2 * time.Second
+	RetryDefaultMaxBackoff     = time.Minute
+	RetryDefaultMultiplier     = 2
+)
+
+const (
+	MessageTransportGoChannel   = "gochannel"
+	MessageTransportRedisStream = "redisstream"
+
+	RedisStreamConsumerGroup   = "video_processor"
+	RedisStreamMaxLen          = 10000
+	RedisStreamBlockTime       = 5 * time.Second
+	RedisStreamClaimInterval   = time.Minute
+	RedisStreamMinIdleTime     = 10 * time.Minute
+	RedisStreamRefreshInterval = 2 * time.Minute
+	RedisStreamMaxInFlight     = 4
+	RedisStreamNackResendSleep = 5 * time.Second
+
+	// Every instance receives an upload announced on the redis channel, the first one to set
+	// the bridge key publishes it
+	RedisBridgeKeyPrefix = "video_processor:bridged:"
+	RedisBridgeKeyTTL    = 24 * time.Hour
 )
 
 const (
 	MaxConcurrentS3Push  = 50
 	AWSVideoS3BuckerName = "hls-video-segment"
 	AWSRegion            = "ap-southeast-1"
+
+	// Objects from MultipartUploadThreshold bytes up are uploaded in parts of at least
+	// MultipartPartSize bytes, MultipartUploadConcurrency parts of a file at a time
+	MultipartUploadThreshold   = 16 << 20
+	MultipartPartSize          = 8 << 20
+	MultipartUploadConcurrency = 4
+	UploadStateDir             = "upload_state"
+	DownloadChunkSize          = 16 << 20
+
+	PlaylistCacheControl = "public, max-age=60"
+	SegmentCacheControl  = "public, max-age=31536000, immutable"
+	ImageCacheControl    = "public, max-age=86400"
+
+	StorageBackendS3       = "s3"
+	StorageBackendLocal    = "local"
+	DefaultLocalStorageDir = "storage"
 )
@@ -2,13 +2,19 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
+	"video_processor/hlssegmenter"
+	"video_processor/jobstore"
+	"video_processor/jobtracker"
 	"video_processor/logger"
 	"video_processor/messagemodel"
 	pb "video_processor/proto/video_service/video_service" // import the generated protobuf package
+	"video_processor/storagehandler"
 	"video_processor/watermill"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type VideoServiceServer struct {
@@ -18,15 +24,251 @@ type VideoServiceServer struct {
 func (s *VideoServiceServer) ProcessNewVideoRequest(ctx context.Context, req *pb.VideoInfo) (*pb.ProcessNewVideoResponse, error) {
 
 	videoInfo := messagemodel.VideoInfo{
-		RawVidS3Key: req.S3Key,
-		Timestamp:   req.Timestamp,
-		CourseId:    req.CourseId,
-		VideoId:     req.VideoId,
-		UploadedBy:  req.UploadedBy,
+		RawVidS3Key:         req.S3Key,
+		Timestamp:           req.Timestamp,
+		CourseId:            req.CourseId,
+		VideoId:             req.VideoId,
+		UploadedBy:          req.UploadedBy,
+		Renditions:          toRenditions(req.Renditions),
+		ScaleMode:           req.ScaleMode,
+		Packaging:           req.Packaging,
+		Dash:                req.Dash,
+		Encrypt:             req.Encrypt,
+		KeyRotationSegments: int(req.KeyRotationSegments),
+		DemuxAudio:          req.DemuxAudio,
+		AudioOnlyVariant:    req.AudioOnlyVariant,
+		Subtitles:           toSubtitles(req.Subtitles),
+		Thumbnails:          req.Thumbnails,
+		ThumbnailInterval:   int(req.ThumbnailInterval),
+		Poster:              req.Poster,
 	}
 
 	logger.AppLogger.Info("videoInfo", zap.Any("videoInfo", videoInfo))
 
-	go watermill.PublishVideoUploadedEvent(&videoInfo)
-	return &pb.ProcessNewVideoResponse{Status: codes.OK.String()}, nil
+	if _, err := hlssegmenter.ResolveOptions(videoInfo); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// A video that is queued, being processed or whose source did not change is not processed again
+	etag, err := storagehandler.GetObjectETag(ctx, videoInfo.RawVidS3Key)
+	if err == nil && jobstore.HasCompleted(videoInfo.VideoId, videoInfo.RawVidS3Key, etag) {
+		return completedResponse(videoInfo.VideoId), nil
+	}
+	if err := jobtracker.Enqueue(videoInfo.VideoId); errors.Is(err, jobtracker.ErrJobRunning) {
+		job, _ := jobtracker.GetStatus(videoInfo.VideoId)
+		return &pb.ProcessNewVideoResponse{Status: codes.OK.String(), Stage: string(job.Stage), Duplicate: true}, nil
+	}
+
+	// Persisted before it is published, a crash before a handler picks it up must not lose it
+	jobstore.SaveVideoInfo(videoInfo)
+	if err := watermill.PublishVideoUploadedEvent(&videoInfo); err != nil {
+		// Release the claim, otherwise every retry of the caller is answered as a duplicate
+		jobtracker.SetFailed(videoInfo.VideoId, err)
+		return nil, status.Errorf(codes.Unavailable, "cannot queue video %s: %v", videoInfo.VideoId, err)
+	}
+	return &pb.ProcessNewVideoResponse{Status: codes.OK.String(), Stage: string(jobtracker.StageQueued)}, nil
+}
+
+// completedResponse answers a duplicate of a processed video with where its output was uploaded
+func completedResponse(videoId string) *pb.ProcessNewVideoResponse {
+	response := &pb.ProcessNewVideoResponse{Status: codes.OK.String(), Stage: string(jobtracker.StageDone), Duplicate: true}
+
+	job, err := jobstore.GetJob(videoId)
+	if err != nil || job.UploadedSegments == nil {
+		return response
+	}
+	response.KeyPrefix = job.UploadedSegments.KeyPrefix
+	response.MasterPlaylistKey = job.UploadedSegments.MasterPlaylistKey
+	response.DashManifestKey = job.UploadedSegments.DashManifestKey
+	return response
+}
+
+func toRenditions(renditions []*pb.Rendition) []messagemodel.Rendition {
+	result := make([]messagemodel.Rendition, 0, len(renditions))
+	for _, rendition := range renditions {
+		result = append(result, messagemodel.Rendition{
+			Name:             rendition.Name,
+			Width:            int(rendition.Width),
+			Height:           int(rendition.Height),
+			VideoBitrateKbps: int(rendition.VideoBitrateKbps),
+			MaxrateKbps:      int(rendition.MaxrateKbps),
+			BufsizeKbps:      int(rendition.BufsizeKbps),
+			AudioBitrateKbps: int(rendition.AudioBitrateKbps),
+			SegmentDuration:  int(rendition.SegmentDuration),
+			Codec:            rendition.Codec,
+		})
+	}
+	return result
+}
+
+func toSubtitles(subtitles []*pb.Subtitle) []messagemodel.Subtitle {
+	result := make([]messagemodel.Subtitle, 0, len(subtitles))
+	for _, subtitle := range subtitles {
+		result = append(result, messagemodel.Subtitle{
+			S3Key:    subtitle.S3Key,
+			Language: subtitle.Language,
+			Name:     subtitle.Name,
+		})
+	}
+	return result
+}
+
+func (s *VideoServiceServer) GetProcessingStatus(ctx context.Context, req *pb.ProcessingStatusRequest) (*pb.ProcessingStatusResponse, error) {
+	if req.VideoId == "" {
+		return nil, status.Error(codes.InvalidArgument, "video_id is required")
+	}
+
+	job, ok := jobtracker.GetStatus(req.VideoId)
+	if !ok {
+		job, ok = storedJobStatus(req.VideoId)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no processing job for video %s", req.VideoId)
+	}
+
+	renditions := make([]*pb.RenditionProgress, 0, len(job.Renditions))
+	for _, rendition := range job.Renditions {
+		renditions = append(renditions, &pb.RenditionProgress{
+			Name:       rendition.Name,
+			Percentage: rendition.Percentage,
+		})
+	}
+
+	return &pb.ProcessingStatusResponse{
+		VideoId:    job.VideoId,
+		Stage:      string(job.Stage),
+		Renditions: renditions,
+		Error:      job.Error,
+		UpdatedAt:  job.UpdatedAt.Unix(),
+	}, nil
+}
+
+// storedJobStatus reads a job that is not tracked anymore, e.g. it finished before the last
+// restart, from the job store
+func storedJobStatus(videoId string) (jobtracker.JobStatus, bool) {
+	storedJob, err := jobstore.GetJob(videoId)
+	if err != nil {
+		return jobtracker.JobStatus{}, false
+	}
+
+	var lastError string
+	if len(storedJob.Errors) > 0 {
+		lastError = storedJob.Errors[len(storedJob.Errors)-1]
+	}
+	return jobtracker.JobStatus{
+		VideoId:   videoId,
+		Stage:     jobtracker.Stage(storedJob.Stage),
+		Error:     lastError,
+		UpdatedAt: storedJob.UpdatedAt,
+	}, true
+}
+
+func (s *VideoServiceServer) CancelProcessing(ctx context.Context, req *pb.CancelProcessingRequest) (*pb.CancelProcessingResponse, error) {
+	if req.VideoId == "" {
+		return nil, status.Error(codes.InvalidArgument, "video_id is required")
+	}
+
+	err := jobtracker.Cancel(req.VideoId)
+	switch {
+	case errors.Is(err, jobtracker.ErrJobNotFound):
+		return nil, status.Errorf(codes.NotFound, "no processing job for video %s", req.VideoId)
+	case errors.Is(err, jobtracker.ErrJobNotRunning):
+		job, _ := jobtracker.GetStatus(req.VideoId)
+		return nil, status.Errorf(codes.FailedPrecondition, "job for video %s is already %s", req.VideoId, job.Stage)
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.CancelProcessingResponse{Status: string(jobtracker.StageCancelled)}, nil
+}
+
+func (s *VideoServiceServer) WatchProcessing(req *pb.ProcessingStatusRequest, stream pb.VideoProcessingService_WatchProcessingServer) error {
+	if req.VideoId == "" {
+		return status.Error(codes.InvalidArgument, "video_id is required")
+	}
+
+	// Subscribe before reading the snapshot so no change in between is lost
+	events, unsubscribe := jobtracker.Subscribe(req.VideoId)
+	defer unsubscribe()
+
+	job, ok := jobtracker.GetStatus(req.VideoId)
+	if !ok {
+		// Nothing changes a job that is only in the job store, its state is all there is to send
+		storedJob, ok := storedJobStatus(req.VideoId)
+		if !ok {
+			return status.Errorf(codes.NotFound, "no processing job for video %s", req.VideoId)
+		}
+		events := snapshotEvents(storedJob)
+		return stream.Send(toProcessingEvent(events[len(events)-1]))
+	}
+
+	for _, event := range snapshotEvents(job) {
+		if err := stream.Send(toProcessingEvent(event)); err != nil {
+			return err
+		}
+		if event.IsFinal() {
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case event := <-events:
+			if err := stream.Send(toProcessingEvent(event)); err != nil {
+				logger.AppLogger.Error("Failed to send processing event", zap.Error(err), zap.String("videoId", req.VideoId))
+				return err
+			}
+			if event.IsFinal() {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// snapshotEvents replays the current job state so a new watcher starts with the full picture
+func snapshotEvents(job jobtracker.JobStatus) []jobtracker.Event {
+	events := make([]jobtracker.Event, 0, len(job.Renditions)+1)
+
+	for _, rendition := range job.Renditions {
+		events = append(events, jobtracker.Event{
+			VideoId:    job.VideoId,
+			Type:       jobtracker.EventProgress,
+			Stage:      job.Stage,
+			Rendition:  rendition.Name,
+			Percentage: rendition.Percentage,
+			Timestamp:  job.UpdatedAt,
+		})
+	}
+
+	stageEvent := jobtracker.Event{
+		VideoId:   job.VideoId,
+		Type:      jobtracker.EventStage,
+		Stage:     job.Stage,
+		Error:     job.Error,
+		Timestamp: job.UpdatedAt,
+	}
+	switch job.Stage {
+	case jobtracker.StageDone:
+		stageEvent.Type = jobtracker.EventSucceeded
+	case jobtracker.StageFailed:
+		stageEvent.Type = jobtracker.EventFailed
+	case jobtracker.StageCancelled:
+		stageEvent.Type = jobtracker.EventCancelled
+	}
+
+	return append(events, stageEvent)
+}
+
+func toProcessingEvent(event jobtracker.Event) *pb.ProcessingEvent {
+	return &pb.ProcessingEvent{
+		VideoId:    event.VideoId,
+		Type:       string(event.Type),
+		Stage:      string(event.Stage),
+		Rendition:  event.Rendition,
+		Percentage: event.Percentage,
+		Error:      event.Error,
+		Timestamp:  event.Timestamp.Unix(),
+	}
 }
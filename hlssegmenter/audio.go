@@ -0,0 +1,136 @@
+package hlssegmenter
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"video_processor/resolutionparser"
+)
+
+const (
+	// audioGroupId is the GROUP-ID of the demuxed audio renditions in the master playlist
+	audioGroupId = "audio"
+	// maxAudioChannels downmixes surround tracks to stereo, which every HLS player can decode
+	maxAudioChannels = 2
+	audioSampleRate  = 48000
+)
+
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]+)*$`)
+
+// AudioRendition is a demuxed audio track of the output, one per audio stream of the source
+type AudioRendition struct {
+	Name        string
+	StreamIndex int
+	Language    string
+	// Label is the NAME of the rendition, unique within the audio group
+	Label           string
+	Channels        int
+	BitrateKbps     int
+	SegmentDuration int
+	Default         bool
+}
+
+// audioRenditions turns the audio streams of the source into renditions encoded at the
+// highest audio bitrate of the ladder
+func audioRenditions(streams []resolutionparser.AudioStream, ladder []Resolution) []AudioRendition {
+	bitrate := defaultAudioBitrateKbps
+	for _, res := range ladder {
+		bitrate = max(bitrate, res.AudioBitrateKbps)
+	}
+
+	hasDefault := false
+	for _, stream := range streams {
+		hasDefault = hasDefault || stream.Default
+	}
+
+	renditions := make([]AudioRendition, 0, len(streams))
+	names := make(map[string]bool, len(streams))
+	labels := make(map[string]bool, len(streams))
+	for i, stream := range streams {
+		language := stream.Language
+		if language == "und" || !languagePattern.MatchString(language) {
+			language = ""
+		}
+
+		// The language names the directory unless two tracks share it
+		name := fmt.Sprintf("audio_%d", stream.Index)
+		if language != "" && !names["audio_"+language] {
+			name = "audio_" + language
+		}
+		names[name] = true
+
+		label := audioLabel(stream, language)
+		if labels[label] {
+			label = fmt.Sprintf("%s (%d)", label, stream.Index+1)
+		}
+		labels[label] = true
+
+		channels := stream.Channels
+		if channels <= 0 || channels > maxAudioChannels {
+			channels = maxAudioChannels
+		}
+
+		renditions = append(renditions, AudioRendition{
+			Name:            name,
+			StreamIndex:     stream.Index,
+			Language:        language,
+			Label:           label,
+			Channels:        channels,
+			BitrateKbps:     bitrate,
+			SegmentDuration: defaultSegmentDuration,
+			Default:         stream.Default || (!hasDefault && i == 0),
+		})
+	}
+
+	// Only one rendition of a group may be the default
+	seenDefault := false
+	for i := range renditions {
+		if renditions[i].Default && seenDefault {
+			renditions[i].Default = false
+		}
+		seenDefault = seenDefault || renditions[i].Default
+	}
+
+	return renditions
+}
+
+// audioLabel is the NAME shown in the player's audio menu
+func audioLabel(stream resolutionparser.AudioStream, language string) string {
+	if stream.Title != "" {
+		return stream.Title
+	}
+	if language != "" {
+		return language
+	}
+	return fmt.Sprintf("Audio %d", stream.Index+1)
+}
+
+func generateAudioFFmpegCommand(ctx context.Context, inputFile, outputDir, playlistName string, audio AudioRendition, segmentPackaging Packaging) (*exec.Cmd, error) {
+	outputPath := filepath.Join(outputDir, segmentFilePrefix+"%03d."+segmentPackaging.segmentExtension())
+	playlistPath := filepath.Join(outputDir, playlistName)
+
+	args := []string{
+		"-i", inputFile,
+		"-map", fmt.Sprintf("0:a:%d", audio.StreamIndex),
+		"-vn",
+		"-c:a", "aac",
+		"-ar", fmt.Sprintf("%d", audioSampleRate),
+		"-ac", fmt.Sprintf("%d", audio.Channels),
+		"-b:a", fmt.Sprintf("%dk", audio.BitrateKbps),
+		"-start_number", "0",
+		"-hls_time", fmt.Sprintf("%d", audio.SegmentDuration),
+		"-hls_list_size", "0",
+		"-f", "hls",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_type", segmentPackaging.segmentType(),
+		"-hls_segment_filename", outputPath,
+	}
+	if segmentPackaging == PackagingFMP4 {
+		args = append(args, "-hls_fmp4_init_filename", fmp4InitFilename)
+	}
+	args = append(args, playlistPath)
+
+	return exec.CommandContext(ctx, "ffmpeg", args...), nil
+}
@@ -0,0 +1,167 @@
+package hlssegmenter
+
+import (
+	"fmt"
+	"math"
+)
+
+// VideoCodec is the encoder of a rendition
+type VideoCodec string
+
+const (
+	// CodecH264 encodes with libx264, the only codec every HLS player decodes
+	CodecH264 VideoCodec = "h264"
+	// CodecHEVC encodes with libx265, tagged hvc1 as Apple players require
+	CodecHEVC VideoCodec = "hevc"
+	// CodecAV1 encodes with libsvtav1
+	CodecAV1 VideoCodec = "av1"
+)
+
+// defaultFrameRate is assumed when the frame rate of the source is unknown
+const defaultFrameRate = 30
+
+func toVideoCodec(value string) (VideoCodec, error) {
+	switch VideoCodec(value) {
+	case "":
+		return CodecH264, nil
+	case CodecH264, CodecHEVC, CodecAV1:
+		return VideoCodec(value), nil
+	}
+	return "", fmt.Errorf("invalid codec %q", value)
+}
+
+// needsFMP4 reports whether HLS players only accept the codec in fragmented MP4
+func (c VideoCodec) needsFMP4() bool {
+	return c == CodecHEVC || c == CodecAV1
+}
+
+// codecLevel is a level of a codec with the limits that decide whether a rendition fits it
+type codecLevel struct {
+	Name string
+	// Id is the value of the level in the codecs string
+	Id            int
+	MaxFrameSize  int64 // luma samples per frame
+	MaxSampleRate int64 // luma samples per second
+	MaxBitrate    int   // kbps, main profile and main tier
+}
+
+// H.264 limits from Table A-1, macroblocks converted to luma samples
+var h264Levels = []codecLevel{
+	{"3.0", 30, 1620 * 256, 40500 * 256, 10000},
+	{"3.1", 31, 3600 * 256, 108000 * 256, 14000},
+	{"3.2", 32, 5120 * 256, 216000 * 256, 20000},
+	{"4.0", 40, 8192 * 256, 245760 * 256, 20000},
+	{"4.1", 41, 8192 * 256, 245760 * 256, 50000},
+	{"4.2", 42, 8704 * 256, 522240 * 256, 50000},
+	{"5.0", 50, 22080 * 256, 589824 * 256, 135000},
+	{"5.1", 51, 36864 * 256, 983040 * 256, 240000},
+	{"5.2", 52, 36864 * 256, 2073600 * 256, 240000},
+}
+
+// HEVC limits from Table A.8, main tier
+var hevcLevels = []codecLevel{
+	{"3.0", 90, 552960, 16588800, 6000},
+	{"3.1", 93, 983040, 33177600, 10000},
+	{"4.0", 120, 2228224, 66846720, 12000},
+	{"4.1", 123, 2228224, 133693440, 20000},
+	{"5.0", 150, 8912896, 267386880, 25000},
+	{"5.1", 153, 8912896, 534773760, 40000},
+	{"5.2", 156, 8912896, 1069547520, 60000},
+}
+
+// AV1 limits from Annex A.3, main tier, the id is seq_level_idx
+var av1Levels = []codecLevel{
+	{"2.0", 0, 147456, 4423680, 1500},
+	{"2.1", 1, 278784, 8363520, 3000},
+	{"3.0", 4, 665856, 19975680, 6000},
+	{"3.1", 5, 1065024, 31950720, 10000},
+	{"4.0", 8, 2359296, 70778880, 12000},
+	{"4.1", 9, 2359296, 141557760, 20000},
+	{"5.0", 12, 8912896, 267386880, 30000},
+	{"5.1", 13, 8912896, 534773760, 40000},
+	{"5.2", 14, 8912896, 1069547520, 60000},
+}
+
+// assignCodecs picks the profile and level of every rung from its output size and frame rate
+func assignCodecs(ladder []Resolution, frameRate float64) {
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
+
+	for i := range ladder {
+		res := &ladder[i]
+		frameSize := int64(res.OutputWidth) * int64(res.OutputHeight)
+		sampleRate := int64(math.Ceil(float64(frameSize) * frameRate))
+
+		switch res.Codec {
+		case CodecHEVC:
+			level := pickLevel(hevcLevels, frameSize, sampleRate, res.MaxrateKbps, 1)
+			res.Profile = "main"
+			res.Level = level.Name
+			res.Codecs = fmt.Sprintf("hvc1.1.6.L%d.B0", level.Id)
+		case CodecAV1:
+			level := pickLevel(av1Levels, frameSize, sampleRate, res.MaxrateKbps, 1)
+			res.Profile = "main"
+			res.Level = level.Name
+			res.Codecs = fmt.Sprintf("av01.0.%02dM.08", level.Id)
+		default:
+			// High gives the larger renditions about 10% more quality for the bits, small ones
+			// stay on Main for older phones
+			profile, profileIdc, constraints, bitrateFactor := "main", 0x4D, 0x40, 1.0
+			if min(res.OutputWidth, res.OutputHeight) > 480 {
+				profile, profileIdc, constraints, bitrateFactor = "high", 0x64, 0x00, 1.25
+			}
+			level := pickLevel(h264Levels, frameSize, sampleRate, res.MaxrateKbps, bitrateFactor)
+			res.Profile = profile
+			res.Level = level.Name
+			res.Codecs = fmt.Sprintf("avc1.%02X%02X%02X", profileIdc, constraints, level.Id)
+		}
+	}
+}
+
+// pickLevel returns the lowest level the rendition fits in, or the highest one
+func pickLevel(levels []codecLevel, frameSize, sampleRate int64, maxrateKbps int, bitrateFactor float64) codecLevel {
+	for _, level := range levels {
+		if frameSize <= level.MaxFrameSize && sampleRate <= level.MaxSampleRate &&
+			float64(maxrateKbps) <= float64(level.MaxBitrate)*bitrateFactor {
+			return level
+		}
+	}
+	return levels[len(levels)-1]
+}
+
+// encoderArgs are the FFmpeg arguments that select and configure the encoder of a rendition
+func encoderArgs(res Resolution) []string {
+	switch res.Codec {
+	case CodecHEVC:
+		return []string{
+			"-c:v", "libx265",
+			"-tag:v", "hvc1",
+			"-profile:v", res.Profile,
+			// Main profile and the advertised codecs string are 8 bit, 10 bit sources are converted
+			"-pix_fmt", "yuv420p",
+			"-x265-params", "level-idc=" + res.Level + ":log-level=error",
+			"-b:v", fmt.Sprintf("%dk", res.VideoBitrateKbps),
+			"-maxrate", fmt.Sprintf("%dk", res.MaxrateKbps),
+			"-bufsize", fmt.Sprintf("%dk", res.BufsizeKbps),
+		}
+	case CodecAV1:
+		// SVT-AV1 takes a target bitrate, it has no VBV buffer to cap
+		return []string{
+			"-c:v", "libsvtav1",
+			"-preset", "8",
+			"-pix_fmt", "yuv420p",
+			"-b:v", fmt.Sprintf("%dk", res.VideoBitrateKbps),
+		}
+	default:
+		return []string{
+			"-c:v", "libx264",
+			"-profile:v", res.Profile,
+			"-level:v", res.Level,
+			"-pix_fmt", "yuv420p",
+			"-b:v", fmt.Sprintf("%dk", res.VideoBitrateKbps),
+			"-maxrate", fmt.Sprintf("%dk", res.MaxrateKbps),
+			"-bufsize", fmt.Sprintf("%dk", res.BufsizeKbps),
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package hlssegmenter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAssignCodecs(t *testing.T) {
+	tests := []struct {
+		name        string
+		res         Resolution
+		frameRate   float64
+		wantProfile string
+		wantLevel   string
+		wantCodecs  string
+	}{
+		{
+			name:        "h264 1080p30 on high",
+			res:         Resolution{Codec: CodecH264, OutputWidth: 1920, OutputHeight: 1080, MaxrateKbps: 5350},
+			frameRate:   30,
+			wantProfile: "high",
+			wantLevel:   "4.0",
+			wantCodecs:  "avc1.640028",
+		},
+		{
+			name:        "h264 1080p60 needs a higher level",
+			res:         Resolution{Codec: CodecH264, OutputWidth: 1920, OutputHeight: 1080, MaxrateKbps: 5350},
+			frameRate:   60,
+			wantProfile: "high",
+			wantLevel:   "4.2",
+			wantCodecs:  "avc1.64002A",
+		},
+		{
+			name:        "h264 360p stays on main",
+			res:         Resolution{Codec: CodecH264, OutputWidth: 640, OutputHeight: 360, MaxrateKbps: 856},
+			frameRate:   30,
+			wantProfile: "main",
+			wantLevel:   "3.0",
+			wantCodecs:  "avc1.4D401E",
+		},
+		{
+			name:        "unknown frame rate is taken as 30",
+			res:         Resolution{Codec: CodecH264, OutputWidth: 640, OutputHeight: 360, MaxrateKbps: 856},
+			wantProfile: "main",
+			wantLevel:   "3.0",
+			wantCodecs:  "avc1.4D401E",
+		},
+		{
+			name:        "bitrate above the level limit",
+			res:         Resolution{Codec: CodecH264, OutputWidth: 640, OutputHeight: 360, MaxrateKbps: 12000},
+			frameRate:   30,
+			wantProfile: "main",
+			wantLevel:   "3.1",
+			wantCodecs:  "avc1.4D401F",
+		},
+		{
+			name:        "hevc",
+			res:         Resolution{Codec: CodecHEVC, OutputWidth: 1920, OutputHeight: 1080, MaxrateKbps: 5350},
+			frameRate:   30,
+			wantProfile: "main",
+			wantLevel:   "4.0",
+			wantCodecs:  "hvc1.1.6.L120.B0",
+		},
+		{
+			name:        "av1",
+			res:         Resolution{Codec: CodecAV1, OutputWidth: 1280, OutputHeight: 720, MaxrateKbps: 2996},
+			frameRate:   30,
+			wantProfile: "main",
+			wantLevel:   "3.1",
+			wantCodecs:  "av01.0.05M.08",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ladder := []Resolution{tt.res}
+			assignCodecs(ladder, tt.frameRate)
+			got := ladder[0]
+			if got.Profile != tt.wantProfile || got.Level != tt.wantLevel || got.Codecs != tt.wantCodecs {
+				t.Errorf("assignCodecs() = %s %s %s, want %s %s %s",
+					got.Profile, got.Level, got.Codecs, tt.wantProfile, tt.wantLevel, tt.wantCodecs)
+			}
+		})
+	}
+}
+
+func TestEncoderArgs(t *testing.T) {
+	tests := []struct {
+		codec       VideoCodec
+		wantEncoder string
+	}{
+		{codec: CodecH264, wantEncoder: "libx264"},
+		{codec: CodecHEVC, wantEncoder: "libx265"},
+		{codec: CodecAV1, wantEncoder: "libsvtav1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.codec), func(t *testing.T) {
+			args := encoderArgs(Resolution{Codec: tt.codec, Profile: "main", Level: "4.0", VideoBitrateKbps: 2000, MaxrateKbps: 2140, BufsizeKbps: 3000})
+
+			if i := slices.Index(args, "-c:v"); i < 0 || args[i+1] != tt.wantEncoder {
+				t.Errorf("encoder args %v do not select %s", args, tt.wantEncoder)
+			}
+			// Every advertised codecs string is 8 bit 4:2:0
+			if i := slices.Index(args, "-pix_fmt"); i < 0 || args[i+1] != "yuv420p" {
+				t.Errorf("encoder args %v do not convert to yuv420p", args)
+			}
+			if i := slices.Index(args, "-b:v"); i < 0 || args[i+1] != "2000k" {
+				t.Errorf("encoder args %v do not set the bitrate", args)
+			}
+		})
+	}
+}
+
+func TestToVideoCodec(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    VideoCodec
+		wantErr bool
+	}{
+		{value: "", want: CodecH264},
+		{value: "h264", want: CodecH264},
+		{value: "hevc", want: CodecHEVC},
+		{value: "av1", want: CodecAV1},
+		{value: "H264", wantErr: true},
+		{value: "vp9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := toVideoCodec(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toVideoCodec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("toVideoCodec() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
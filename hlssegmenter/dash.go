@@ -0,0 +1,216 @@
+package hlssegmenter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	dashManifestName = "manifest.mpd"
+	// dashTimescale is the number of timeline units per second, segment durations are in milliseconds
+	dashTimescale = 1000
+)
+
+type mpd struct {
+	XMLName                   xml.Name  `xml:"MPD"`
+	Xmlns                     string    `xml:"xmlns,attr"`
+	Profiles                  string    `xml:"profiles,attr"`
+	Type                      string    `xml:"type,attr"`
+	MediaPresentationDuration string    `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             string    `xml:"minBufferTime,attr"`
+	Period                    mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ID               int                 `xml:"id,attr"`
+	ContentType      string              `xml:"contentType,attr"`
+	MimeType         string              `xml:"mimeType,attr"`
+	Lang             string              `xml:"lang,attr,omitempty"`
+	SegmentAlignment bool                `xml:"segmentAlignment,attr"`
+	MaxWidth         int                 `xml:"maxWidth,attr,omitempty"`
+	MaxHeight        int                 `xml:"maxHeight,attr,omitempty"`
+	Roles            []mpdRole           `xml:"Role"`
+	Representations  []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdRole struct {
+	SchemeIdUri string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+type mpdRepresentation struct {
+	ID        string `xml:"id,attr"`
+	Bandwidth int    `xml:"bandwidth,attr"`
+	Width     int    `xml:"width,attr,omitempty"`
+	Height    int    `xml:"height,attr,omitempty"`
+	Codecs    string `xml:"codecs,attr,omitempty"`
+	// AudioSamplingRate is only set on audio representations
+	AudioSamplingRate int                `xml:"audioSamplingRate,attr,omitempty"`
+	SegmentTemplate   mpdSegmentTemplate `xml:"SegmentTemplate"`
+}
+
+type mpdSegmentTemplate struct {
+	Timescale      int                `xml:"timescale,attr"`
+	Initialization string             `xml:"initialization,attr"`
+	Media          string             `xml:"media,attr"`
+	StartNumber    int                `xml:"startNumber,attr"`
+	Timeline       []mpdTimelineEntry `xml:"SegmentTimeline>S"`
+}
+
+// mpdTimelineEntry is an S element, R more segments of the same duration follow the first
+type mpdTimelineEntry struct {
+	T int64 `xml:"t,attr"`
+	D int64 `xml:"d,attr"`
+	R int   `xml:"r,attr,omitempty"`
+}
+
+// generateDashManifest writes a DASH manifest next to the master playlist that points at the
+// same CMAF segments, so HLS and DASH players share one copy of the output
+func generateDashManifest(outputDir string, ladder []Resolution, variantPlaylists []string, variantStats []renditionStats, audio []AudioRendition, audioPlaylists []string, audioStats []renditionStats) error {
+	videoSet := mpdAdaptationSet{
+		ID:               0,
+		ContentType:      "video",
+		MimeType:         "video/mp4",
+		SegmentAlignment: true,
+	}
+
+	var duration float64
+	var segmentDuration int
+	for i, playlistName := range variantPlaylists {
+		if playlistName == "" {
+			continue
+		}
+		res := ladder[i]
+
+		representation, playlistDuration, err := dashRepresentation(outputDir, res.Name, playlistName, variantStats[i].PeakBandwidth, res.Codecs)
+		if err != nil {
+			return err
+		}
+		representation.Width = res.OutputWidth
+		representation.Height = res.OutputHeight
+
+		// Players can only switch on segment boundaries shared by every representation
+		if segmentDuration != 0 && segmentDuration != res.SegmentDuration {
+			videoSet.SegmentAlignment = false
+		}
+		segmentDuration = res.SegmentDuration
+		duration = math.Max(duration, playlistDuration)
+
+		videoSet.MaxWidth = max(videoSet.MaxWidth, res.OutputWidth)
+		videoSet.MaxHeight = max(videoSet.MaxHeight, res.OutputHeight)
+		videoSet.Representations = append(videoSet.Representations, representation)
+	}
+
+	adaptationSets := []mpdAdaptationSet{videoSet}
+
+	// Every audio track is an adaptation set of its own, the player picks one by language
+	for i, rendition := range audio {
+		representation, playlistDuration, err := dashRepresentation(outputDir, rendition.Name, audioPlaylists[i], audioStats[i].PeakBandwidth, audioCodecs)
+		if err != nil {
+			return err
+		}
+		representation.AudioSamplingRate = audioSampleRate
+		duration = math.Max(duration, playlistDuration)
+
+		audioSet := mpdAdaptationSet{
+			ID:               len(adaptationSets),
+			ContentType:      "audio",
+			MimeType:         "audio/mp4",
+			Lang:             rendition.Language,
+			SegmentAlignment: true,
+			Representations:  []mpdRepresentation{representation},
+		}
+		if rendition.Default {
+			audioSet.Roles = []mpdRole{{SchemeIdUri: "urn:mpeg:dash:role:2011", Value: "main"}}
+		}
+		adaptationSets = append(adaptationSets, audioSet)
+	}
+
+	manifest := mpd{
+		Xmlns:                     "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:                  "urn:mpeg:dash:profile:isoff-live:2011",
+		Type:                      "static",
+		MediaPresentationDuration: dashDuration(duration),
+		MinBufferTime:             dashDuration(float64(max(segmentDuration, 2))),
+		Period: mpdPeriod{
+			ID:             "0",
+			Start:          "PT0S",
+			AdaptationSets: adaptationSets,
+		},
+	}
+
+	data, err := xml.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode DASH manifest: %v", err)
+	}
+
+	manifestPath := filepath.Join(outputDir, dashManifestName)
+	if err := os.WriteFile(manifestPath, append([]byte(xml.Header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write DASH manifest: %v", err)
+	}
+
+	logger.AppLogger.Info("DASH manifest generated",
+		zap.String("path", manifestPath),
+		zap.Int("representations", len(videoSet.Representations)),
+		zap.Int("audioTracks", len(audio)))
+	return nil
+}
+
+// dashRepresentation describes the segments of one rendition directory from its variant playlist
+func dashRepresentation(outputDir, name, playlistName string, bandwidth int, codecs string) (mpdRepresentation, float64, error) {
+	playlist, err := parseMediaPlaylist(filepath.Join(outputDir, name, playlistName))
+	if err != nil {
+		return mpdRepresentation{}, 0, fmt.Errorf("rendition %s: %v", name, err)
+	}
+	if playlist.MapURI == "" {
+		return mpdRepresentation{}, 0, fmt.Errorf("rendition %s: DASH needs fmp4 segments", name)
+	}
+
+	return mpdRepresentation{
+		ID:        name,
+		Bandwidth: bandwidth,
+		Codecs:    codecs,
+		SegmentTemplate: mpdSegmentTemplate{
+			Timescale:      dashTimescale,
+			Initialization: name + "/" + playlist.MapURI,
+			Media:          name + "/" + segmentFilePrefix + "$Number%03d$." + PackagingFMP4.segmentExtension(),
+			StartNumber:    0,
+			Timeline:       segmentTimeline(playlist.Segments),
+		},
+	}, playlist.Duration(), nil
+}
+
+// segmentTimeline turns the EXTINF durations into S elements, merging runs of equal durations.
+// Start times are rounded from the running total so rounding errors do not add up.
+func segmentTimeline(segments []mediaSegment) []mpdTimelineEntry {
+	var timeline []mpdTimelineEntry
+	var elapsed float64
+	for _, segment := range segments {
+		start := int64(math.Round(elapsed * dashTimescale))
+		elapsed += segment.Duration
+		length := int64(math.Round(elapsed*dashTimescale)) - start
+
+		if n := len(timeline); n > 0 && timeline[n-1].D == length {
+			timeline[n-1].R++
+			continue
+		}
+		timeline = append(timeline, mpdTimelineEntry{T: start, D: length})
+	}
+	return timeline
+}
+
+func dashDuration(seconds float64) string {
+	return fmt.Sprintf("PT%.3fS", seconds)
+}
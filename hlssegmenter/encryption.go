@@ -0,0 +1,183 @@
+package hlssegmenter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"video_processor/appconst"
+	"video_processor/keystore"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+// contentKey encrypts one run of segments of every rendition
+type contentKey struct {
+	id  string
+	key []byte
+	// ivBase is random per key, every segment gets its own IV derived from it
+	ivBase []byte
+}
+
+// encryptRenditions encrypts the segments written by FFmpeg with AES-128 and adds EXT-X-KEY
+// tags to the playlists, given relative to outputDir. A new key starts every rotateEvery
+// segments, or a single key covers the whole video when rotateEvery is 0. The keys go to
+// the key store, never to the output. No two segments share an IV under the same key.
+func encryptRenditions(ctx context.Context, videoId, outputDir string, playlistPaths []string, rotateEvery int) error {
+	var keys []contentKey
+
+	for renditionIndex, playlistPath := range playlistPaths {
+		playlistPath = filepath.Join(outputDir, playlistPath)
+		renditionDir := filepath.Dir(playlistPath)
+
+		playlist, err := parseMediaPlaylist(playlistPath)
+		if err != nil {
+			return fmt.Errorf("rendition %s: %v", filepath.Base(renditionDir), err)
+		}
+
+		for segmentIndex, segment := range playlist.Segments {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			keyIndex := keyIndexOf(segmentIndex, rotateEvery)
+			for len(keys) <= keyIndex {
+				key, err := newContentKey(videoId, len(keys))
+				if err != nil {
+					return err
+				}
+				keys = append(keys, key)
+			}
+
+			iv := segmentIV(keys[keyIndex], renditionIndex, segmentIndex)
+			if err := encryptFile(filepath.Join(renditionDir, segment.URI), keys[keyIndex], iv); err != nil {
+				return fmt.Errorf("rendition %s: %v", filepath.Base(renditionDir), err)
+			}
+		}
+
+		if err := addKeyTags(playlistPath, videoId, keys, rotateEvery, renditionIndex); err != nil {
+			return fmt.Errorf("rendition %s: %v", filepath.Base(renditionDir), err)
+		}
+
+		logger.AppLogger.Info("Rendition encrypted",
+			zap.String("videoId", videoId),
+			zap.String("resolution", filepath.Base(renditionDir)),
+			zap.Int("segments", len(playlist.Segments)))
+	}
+
+	return nil
+}
+
+func keyIndexOf(segmentIndex, rotateEvery int) int {
+	if rotateEvery <= 0 {
+		return 0
+	}
+	return segmentIndex / rotateEvery
+}
+
+func newContentKey(videoId string, index int) (contentKey, error) {
+	key, err := keystore.GenerateKey()
+	if err != nil {
+		return contentKey{}, err
+	}
+
+	ivBase := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(ivBase); err != nil {
+		return contentKey{}, fmt.Errorf("failed to generate IV: %v", err)
+	}
+
+	keyId := fmt.Sprintf("key_%d", index)
+	if err := keystore.Store.PutKey(videoId, keyId, key); err != nil {
+		logger.AppLogger.Error("Failed to store content key", zap.Error(err), zap.String("videoId", videoId), zap.String("keyId", keyId))
+		return contentKey{}, err
+	}
+
+	return contentKey{id: keyId, key: key, ivBase: ivBase}, nil
+}
+
+// segmentIV mixes the rendition and segment index into the low 8 bytes of the IV base of
+// the key. CBC leaks whether two segments start alike when they share a key and an IV.
+func segmentIV(key contentKey, renditionIndex, segmentIndex int) []byte {
+	iv := bytes.Clone(key.ivBase)
+	counter := uint64(renditionIndex)<<32 | uint64(uint32(segmentIndex))
+	for i := 0; i < 8; i++ {
+		iv[aes.BlockSize-1-i] ^= byte(counter >> (8 * i))
+	}
+	return iv
+}
+
+// encryptFile replaces the file with its AES-128-CBC ciphertext with PKCS#7 padding,
+// which is the whole-segment encryption of METHOD=AES-128
+func encryptFile(path string, key contentKey, iv []byte) error {
+	plaintext, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read segment: %v", err)
+	}
+
+	block, err := aes.NewCipher(key.key)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, ciphertext, 0644); err != nil {
+		return fmt.Errorf("failed to write encrypted segment: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace segment: %v", err)
+	}
+	return nil
+}
+
+// addKeyTags writes an EXT-X-KEY before every segment, each one carries the IV of its segment
+func addKeyTags(playlistPath, videoId string, keys []contentKey, rotateEvery, renditionIndex int) error {
+	data, err := os.ReadFile(playlistPath)
+	if err != nil {
+		return fmt.Errorf("failed to read playlist: %v", err)
+	}
+
+	var out bytes.Buffer
+	segmentIndex := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#EXTINF:") {
+			key := keys[keyIndexOf(segmentIndex, rotateEvery)]
+			iv := segmentIV(key, renditionIndex, segmentIndex)
+			fmt.Fprintf(&out, "#EXT-X-KEY:METHOD=AES-128,URI=\"%s\",IV=0x%s\n",
+				keyURI(videoId, key.id), strings.ToUpper(hex.EncodeToString(iv)))
+			segmentIndex++
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read playlist: %v", err)
+	}
+
+	if err := os.WriteFile(playlistPath, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write playlist: %v", err)
+	}
+	return nil
+}
+
+// keyURI is where players fetch a key from, HLS_KEY_URI_TEMPLATE with {video_id} and {key_id}
+func keyURI(videoId, keyId string) string {
+	template := os.Getenv("HLS_KEY_URI_TEMPLATE")
+	if template == "" {
+		template = appconst.DefaultHLSKeyURITemplate
+	}
+	return strings.NewReplacer("{video_id}", videoId, "{key_id}", keyId).Replace(template)
+}
@@ -0,0 +1,55 @@
+package hlssegmenter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+func TestKeyIndexOf(t *testing.T) {
+	tests := []struct {
+		name         string
+		segmentIndex int
+		rotateEvery  int
+		want         int
+	}{
+		{name: "no rotation", segmentIndex: 42, rotateEvery: 0, want: 0},
+		{name: "first key", segmentIndex: 9, rotateEvery: 10, want: 0},
+		{name: "second key", segmentIndex: 10, rotateEvery: 10, want: 1},
+		{name: "every segment", segmentIndex: 7, rotateEvery: 1, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyIndexOf(tt.segmentIndex, tt.rotateEvery); got != tt.want {
+				t.Errorf("keyIndexOf() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentIV(t *testing.T) {
+	key := contentKey{id: "key_0", key: make([]byte, 16), ivBase: bytes.Repeat([]byte{0xa5}, aes.BlockSize)}
+	base := bytes.Clone(key.ivBase)
+
+	seen := make(map[string][2]int)
+	for rendition := 0; rendition < 4; rendition++ {
+		for segment := 0; segment < 300; segment++ {
+			iv := segmentIV(key, rendition, segment)
+			if len(iv) != aes.BlockSize {
+				t.Fatalf("IV is %d bytes, want %d", len(iv), aes.BlockSize)
+			}
+			if previous, ok := seen[string(iv)]; ok {
+				t.Fatalf("rendition %d segment %d reuses the IV of %v", rendition, segment, previous)
+			}
+			seen[string(iv)] = [2]int{rendition, segment}
+		}
+	}
+
+	if !bytes.Equal(key.ivBase, base) {
+		t.Error("segmentIV() modified the IV base of the key")
+	}
+	if !bytes.Equal(segmentIV(key, 0, 0), base) {
+		t.Error("the first segment of the first rendition does not use the IV base")
+	}
+}
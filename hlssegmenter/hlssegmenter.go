@@ -2,6 +2,8 @@ package hlssegmenter
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -13,155 +15,367 @@ import (
 	"sync"
 	"time"
 	"video_processor/appconst"
+	"video_processor/jobtracker"
 	"video_processor/logger"
+	"video_processor/messagemodel"
+	"video_processor/resolutionparser"
+	"video_processor/retry"
 	"video_processor/storagehandler"
 	"video_processor/utils"
 
 	"go.uber.org/zap"
 )
 
-type Resolution struct {
-	Width           int
-	Height          int
-	Name            string
-	SegmentDuration int // New field for segment duration
+// SegmentOutput is what the segmenter leaves in the local output directory for the upload
+type SegmentOutput struct {
+	Dir string
+	// MasterPlaylist, DashManifest and Thumbnails are relative to Dir, DashManifest is empty
+	// without DASH and Thumbnails, the storyboard, without thumbnails
+	MasterPlaylist string
+	DashManifest   string
+	Thumbnails     string
+	Posters        []messagemodel.PosterImage
 }
 
-var resolutions = []Resolution{
-	{Width: 1920, Height: 1080, Name: "1080p", SegmentDuration: 2},
-	{Width: 1280, Height: 720, Name: "720p", SegmentDuration: 3},
-	{Width: 854, Height: 480, Name: "480p", SegmentDuration: 4},
-	{Width: 640, Height: 360, Name: "360p", SegmentDuration: 5},
-}
+func StartSegmentProcess(ctx context.Context, videoInfo messagemodel.VideoInfo, outputDir string) (SegmentOutput, error) {
+	videoId := videoInfo.VideoId
+	rawVidS3Key := videoInfo.RawVidS3Key
+
+	options, err := ResolveOptions(videoInfo)
+	if err != nil {
+		logger.AppLogger.Error("Invalid segment options", zap.Error(err), zap.String("videoId", videoId))
+		return SegmentOutput{}, err
+	}
 
-func StartSegmentProcess(rawVidS3Key, outputDir string) (string, error) {
+	jobtracker.SetStage(videoId, jobtracker.StageDownloading)
 	utils.CreateDirIfNotExist(appconst.UnprecessedVideoDir)
-	unprecessedVideoPath, err := storagehandler.GetS3File(
-		appconst.AWSVideoS3BuckerName,
-		rawVidS3Key,
-		appconst.UnprecessedVideoDir,
-	)
+	var unprecessedVideoPath string
+	err = retry.ForStage(retry.StageS3Download).Do(ctx, rawVidS3Key, func() error {
+		var err error
+		unprecessedVideoPath, err = storagehandler.DownloadFile(ctx, rawVidS3Key, appconst.UnprecessedVideoDir)
+		return err
+	})
 	if err != nil {
-		logger.AppLogger.Error("Failed to get S3 file", zap.Error(err), zap.String("rawVidS3Key", rawVidS3Key))
-		return "", err
+		logger.AppLogger.Error("Failed to get source video", zap.Error(err), zap.String("rawVidS3Key", rawVidS3Key))
+		if ctx.Err() != nil {
+			utils.DeleteLocalFile(filepath.Join(appconst.UnprecessedVideoDir, filepath.Base(rawVidS3Key)))
+		}
+		return SegmentOutput{}, err
+	}
+
+	// Subtitles of different videos may share a file name, each video gets its own directory
+	subtitleDir := filepath.Join(appconst.UnprecessedVideoDir, videoId)
+	for i := range options.Subtitles {
+		track := &options.Subtitles[i]
+		err = retry.ForStage(retry.StageS3Download).Do(ctx, track.S3Key, func() error {
+			var err error
+			track.LocalPath, err = storagehandler.DownloadFile(ctx, track.S3Key, subtitleDir)
+			return err
+		})
+		if err != nil {
+			logger.AppLogger.Error("Failed to get subtitle file", zap.Error(err), zap.String("s3Key", track.S3Key))
+			if ctx.Err() != nil {
+				utils.DeleteLocalFile(unprecessedVideoPath)
+				utils.DeleteDir(subtitleDir)
+			}
+			return SegmentOutput{}, err
+		}
 	}
 
 	utils.CreateDirIfNotExist(rawVidS3Key)
 	excludesExtPath := utils.RemoveFileExtension(rawVidS3Key)
-	err = hslSegmentVideo(unprecessedVideoPath, excludesExtPath)
+	jobtracker.SetStage(videoId, jobtracker.StageSegmenting)
+	output, err := hslSegmentVideo(ctx, videoId, unprecessedVideoPath, excludesExtPath, options)
 	if err != nil {
-		return "", err
+		if ctx.Err() != nil {
+			logger.AppLogger.Info("Segment process cancelled, removing partial output",
+				zap.String("videoId", videoId),
+				zap.String("outputDir", excludesExtPath))
+			utils.DeleteLocalFile(unprecessedVideoPath)
+			utils.DeleteDir(subtitleDir)
+			utils.DeleteDir(excludesExtPath)
+		}
+		return SegmentOutput{}, err
 	}
 
-	return excludesExtPath, nil
+	return output, nil
 }
 
-func hslSegmentVideo(inputFile, outputDir string) error {
+func hslSegmentVideo(ctx context.Context, videoId, inputFile, outputDir string, options SegmentOptions) (SegmentOutput, error) {
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
 		logger.AppLogger.Error("FFmpeg not found. Please install FFmpeg to continue.", zap.Error(err))
-		return err
+		return SegmentOutput{}, err
 	}
 
 	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
 		logger.AppLogger.Error("Failed to create output directory", zap.Error(err), zap.String("outputDir", outputDir))
-		return err
+		return SegmentOutput{}, err
 	}
 
-	// Extract video name from inputFile
-	videoName := filepath.Base(inputFile)
-
-	duration, err := getVideoDuration(inputFile)
+	duration, err := getVideoDuration(ctx, inputFile)
 	if err != nil {
 		logger.AppLogger.Error("Failed to get video duration", zap.Error(err), zap.String("inputFile", inputFile))
-		return err
+		return SegmentOutput{}, err
+	}
+
+	source, err := resolutionparser.GetVideoDimensions(ctx, inputFile)
+	if err != nil {
+		logger.AppLogger.Error("Failed to get video dimensions", zap.Error(err), zap.String("inputFile", inputFile))
+		return SegmentOutput{}, err
+	}
+	logger.AppLogger.Info("Source video dimensions",
+		zap.Int("width", source.Width),
+		zap.Int("height", source.Height),
+		zap.Int("rotation", source.Rotation))
+	ladder := fitLadderToSource(options.Ladder, source, options.ScaleMode)
+	assignCodecs(ladder, source.FrameRate)
+
+	// The CODECS of a variant list the audio too, muxed or from its audio group
+	streams, err := resolutionparser.GetAudioStreams(ctx, inputFile)
+	if err != nil {
+		logger.AppLogger.Error("Failed to get audio streams", zap.Error(err), zap.String("inputFile", inputFile))
+		return SegmentOutput{}, err
+	}
+	hasAudio := len(streams) > 0
+
+	var audio []AudioRendition
+	if options.DemuxAudio {
+		audio = audioRenditions(streams, ladder)
+		logger.AppLogger.Info("Source audio streams", zap.Int("streams", len(streams)))
+	}
+
+	tasks := make([]encodeTask, 0, len(ladder)+len(audio))
+	for _, res := range ladder {
+		res := res
+		tasks = append(tasks, encodeTask{
+			name: res.Name,
+			command: func(dir, playlistName string) (*exec.Cmd, error) {
+				return generateFFmpegCommand(ctx, inputFile, dir, playlistName, res, options.Packaging, !options.DemuxAudio)
+			},
+		})
+	}
+	for _, rendition := range audio {
+		rendition := rendition
+		tasks = append(tasks, encodeTask{
+			name: rendition.Name,
+			command: func(dir, playlistName string) (*exec.Cmd, error) {
+				return generateAudioFFmpegCommand(ctx, inputFile, dir, playlistName, rendition, options.Packaging)
+			},
+		})
 	}
 
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, appconst.VideoMaxConcurrentHLSProcesses)
-	variantPlaylists := make([]string, len(resolutions))
+	playlists := make([]string, len(tasks))
+	stats := make([]renditionStats, len(tasks))
+	var renditionErrors []error
 	var mu sync.Mutex
+	ffmpegPolicy := retry.ForStage(retry.StageFFmpeg)
 
-	for i, res := range resolutions {
+	for i, task := range tasks {
 		wg.Add(1)
-		go func(i int, res Resolution) {
+		go func(i int, task encodeTask) {
 			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			resolutionDir := filepath.Join(outputDir, res.Name)
-			if err := os.MkdirAll(resolutionDir, os.ModePerm); err != nil {
-				logger.AppLogger.Error("Failed to create resolution directory",
-					zap.Error(err),
-					zap.String("resolution", res.Name),
-					zap.String("dir", resolutionDir))
+			if ctx.Err() != nil {
 				return
 			}
 
-			playlistName := fmt.Sprintf("playlist_%s.m3u8", res.Name)
-			cmd, err := generateFFmpegCommand(inputFile, resolutionDir, playlistName, res)
-			if err != nil {
-				logger.AppLogger.Error("Failed to generate FFmpeg command",
+			resolutionDir := filepath.Join(outputDir, task.name)
+			if err := os.MkdirAll(resolutionDir, os.ModePerm); err != nil {
+				logger.AppLogger.Error("Failed to create resolution directory",
 					zap.Error(err),
-					zap.String("resolution", res.Name))
+					zap.String("resolution", task.name),
+					zap.String("dir", resolutionDir))
+				mu.Lock()
+				renditionErrors = append(renditionErrors, fmt.Errorf("rendition %s: %w", task.name, err))
+				mu.Unlock()
 				return
 			}
 
-			stderrPipe, err := cmd.StderrPipe()
+			playlistName := fmt.Sprintf("playlist_%s.m3u8", task.name)
+			err := ffmpegPolicy.Do(ctx, task.name, func() error {
+				// Start every attempt from an empty directory so no stale segment is left behind
+				if err := utils.DeleteDirContents(resolutionDir); err != nil {
+					return err
+				}
+				return runFFmpeg(videoId, task, resolutionDir, playlistName, duration)
+			})
 			if err != nil {
-				logger.AppLogger.Error("Failed to create stderr pipe",
-					zap.Error(err),
-					zap.String("resolution", res.Name))
-				return
-			}
-
-			logger.AppLogger.Info("Starting FFmpeg", zap.String("resolution", res.Name))
-
-			if err := cmd.Start(); err != nil {
-				logger.AppLogger.Error("Failed to start FFmpeg",
-					zap.Error(err),
-					zap.String("resolution", res.Name))
+				mu.Lock()
+				renditionErrors = append(renditionErrors, fmt.Errorf("rendition %s: %w", task.name, err))
+				mu.Unlock()
 				return
 			}
 
-			go monitorProgress(stderrPipe, duration, res.Name)
+			logger.AppLogger.Info("FFmpeg completed successfully", zap.String("resolution", task.name))
 
-			if err := cmd.Wait(); err != nil {
-				logger.AppLogger.Error("FFmpeg command failed",
-					zap.Error(err),
-					zap.String("resolution", res.Name))
+			renditionStats, err := measureRendition(ctx, resolutionDir, playlistName, i < len(ladder), options.Encrypt)
+			if err != nil {
+				logger.AppLogger.Error("Failed to measure rendition", zap.Error(err), zap.String("resolution", task.name))
+				mu.Lock()
+				renditionErrors = append(renditionErrors, fmt.Errorf("rendition %s: %w", task.name, err))
+				mu.Unlock()
 				return
 			}
-
-			logger.AppLogger.Info("FFmpeg completed successfully", zap.String("resolution", res.Name))
+			logger.AppLogger.Info("Measured rendition",
+				zap.String("resolution", task.name),
+				zap.Int("peakBandwidth", renditionStats.PeakBandwidth),
+				zap.Int("averageBandwidth", renditionStats.AverageBandwidth),
+				zap.Float64("frameRate", renditionStats.FrameRate))
+			jobtracker.SetRenditionProgress(videoId, task.name, 100)
 
 			mu.Lock()
-			variantPlaylists[i] = playlistName
+			playlists[i] = playlistName
+			stats[i] = renditionStats
 			logger.AppLogger.Info("Added playlist",
-				zap.String("resolution", res.Name),
+				zap.String("resolution", task.name),
 				zap.Int("index", i),
 				zap.String("playlist", playlistName))
 			mu.Unlock()
 
-			logger.AppLogger.Info("HLS segmentation completed", zap.String("resolution", res.Name))
-		}(i, res)
+			logger.AppLogger.Info("HLS segmentation completed", zap.String("resolution", task.name))
+		}(i, task)
 	}
 
 	wg.Wait()
 
-	logger.AppLogger.Info("Final variant playlists", zap.Strings("playlists", variantPlaylists))
+	if err := ctx.Err(); err != nil {
+		return SegmentOutput{}, err
+	}
+
+	if len(renditionErrors) > 0 {
+		return SegmentOutput{}, errors.Join(renditionErrors...)
+	}
+
+	variantPlaylists, audioPlaylists := playlists[:len(ladder)], playlists[len(ladder):]
+	variantStats, audioStats := stats[:len(ladder)], stats[len(ladder):]
+	logger.AppLogger.Info("Final variant playlists",
+		zap.Strings("playlists", variantPlaylists),
+		zap.Strings("audioPlaylists", audioPlaylists))
+
+	// Byte ranges into segments encrypted as a whole cannot be decrypted on their own
+	var iframes []iframePlaylist
+	if !options.Encrypt {
+		iframes, err = generateIFramePlaylists(outputDir, ladder, variantPlaylists, options.Packaging)
+		if err != nil {
+			logger.AppLogger.Error("Failed to generate I-frame playlists", zap.Error(err), zap.String("videoId", videoId))
+			return SegmentOutput{}, err
+		}
+	}
 
-	generateMasterPlaylist(outputDir, variantPlaylists, utils.RemoveFileExtension(videoName))
+	subtitlePlaylists := make([]string, 0, len(options.Subtitles))
+	for _, track := range options.Subtitles {
+		playlistName, err := segmentSubtitles(track, outputDir, duration, options.Packaging)
+		if err != nil {
+			logger.AppLogger.Error("Failed to segment subtitles", zap.Error(err), zap.String("subtitle", track.Name))
+			return SegmentOutput{}, fmt.Errorf("subtitle %s: %w", track.Name, err)
+		}
+		subtitlePlaylists = append(subtitlePlaylists, playlistName)
+		logger.AppLogger.Info("Subtitles segmented", zap.String("subtitle", track.Name), zap.String("playlist", playlistName))
+	}
+
+	output := SegmentOutput{Dir: outputDir, MasterPlaylist: masterPlaylistName}
+	if options.Thumbnails {
+		err := ffmpegPolicy.Do(ctx, thumbnailsDirName, func() error {
+			return generateThumbnails(ctx, inputFile, outputDir, source, duration, options.ThumbnailInterval)
+		})
+		if err != nil {
+			logger.AppLogger.Error("Failed to generate thumbnails", zap.Error(err), zap.String("videoId", videoId))
+			return SegmentOutput{}, err
+		}
+		output.Thumbnails = thumbnailsDirName + "/" + thumbnailsVTTName
+	}
+
+	if options.Poster {
+		err := ffmpegPolicy.Do(ctx, posterDirName, func() error {
+			var err error
+			output.Posters, err = generatePoster(ctx, inputFile, outputDir, source, duration)
+			return err
+		})
+		if err != nil {
+			logger.AppLogger.Error("Failed to generate poster", zap.Error(err), zap.String("videoId", videoId))
+			return SegmentOutput{}, err
+		}
+	}
+
+	if options.Encrypt {
+		playlistPaths := make([]string, 0, len(tasks))
+		for i, task := range tasks {
+			playlistPaths = append(playlistPaths, filepath.Join(task.name, playlists[i]))
+		}
+		if err := encryptRenditions(ctx, videoId, outputDir, playlistPaths, options.KeyRotationSegments); err != nil {
+			logger.AppLogger.Error("Failed to encrypt renditions", zap.Error(err), zap.String("videoId", videoId))
+			return SegmentOutput{}, err
+		}
+	}
+
+	generateMasterPlaylist(outputDir, ladder, variantPlaylists, variantStats, iframes, audio, audioPlaylists, audioStats, subtitlePlaylists, hasAudio, options)
+
+	if options.DashManifest {
+		if err := generateDashManifest(outputDir, ladder, variantPlaylists, variantStats, audio, audioPlaylists, audioStats); err != nil {
+			logger.AppLogger.Error("Failed to generate DASH manifest", zap.Error(err), zap.String("outputDir", outputDir))
+			return SegmentOutput{}, err
+		}
+		output.DashManifest = dashManifestName
+	}
 
 	logger.AppLogger.Info("HLS segmentation completed successfully for all resolutions",
 		zap.String("outputDir", outputDir))
 
+	return output, nil
+}
+
+// encodeTask is one FFmpeg run writing a rendition into the directory named after it
+type encodeTask struct {
+	name    string
+	command func(dir, playlistName string) (*exec.Cmd, error)
+}
+
+func runFFmpeg(videoId string, task encodeTask, resolutionDir, playlistName string, duration time.Duration) error {
+	cmd, err := task.command(resolutionDir, playlistName)
+	if err != nil {
+		logger.AppLogger.Error("Failed to generate FFmpeg command",
+			zap.Error(err),
+			zap.String("resolution", task.name))
+		return err
+	}
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		logger.AppLogger.Error("Failed to create stderr pipe",
+			zap.Error(err),
+			zap.String("resolution", task.name))
+		return err
+	}
+
+	logger.AppLogger.Info("Starting FFmpeg", zap.String("resolution", task.name))
+
+	if err := cmd.Start(); err != nil {
+		logger.AppLogger.Error("Failed to start FFmpeg",
+			zap.Error(err),
+			zap.String("resolution", task.name))
+		return err
+	}
+
+	go monitorProgress(stderrPipe, duration, videoId, task.name)
+
+	if err := cmd.Wait(); err != nil {
+		logger.AppLogger.Error("FFmpeg command failed",
+			zap.Error(err),
+			zap.String("resolution", task.name))
+		return err
+	}
+
 	return nil
 }
 
-func generateMasterPlaylist(outputDir string, variantPlaylists []string, videoName string) {
+func generateMasterPlaylist(outputDir string, ladder []Resolution, variantPlaylists []string, variantStats []renditionStats, iframes []iframePlaylist, audio []AudioRendition, audioPlaylists []string, audioStats []renditionStats, subtitlePlaylists []string, hasAudio bool, options SegmentOptions) {
 	logger.AppLogger.Info("Generating master playlist", zap.Strings("variantPlaylists", variantPlaylists))
 
-	masterPlaylistPath := filepath.Join(outputDir, "master.m3u8")
+	masterPlaylistPath := filepath.Join(outputDir, masterPlaylistName)
 	f, err := os.Create(masterPlaylistPath)
 	if err != nil {
 		logger.AppLogger.Fatal("Failed to create master playlist",
@@ -171,40 +385,104 @@ func generateMasterPlaylist(outputDir string, variantPlaylists []string, videoNa
 	defer f.Close()
 
 	f.WriteString("#EXTM3U\n")
-	f.WriteString("#EXT-X-VERSION:3\n")
+	f.WriteString(fmt.Sprintf("#EXT-X-VERSION:%d\n", options.Packaging.playlistVersion()))
+
+	for i, rendition := range audio {
+		entry := fmt.Sprintf("#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\",NAME=\"%s\"", audioGroupId, rendition.Label)
+		if rendition.Language != "" {
+			entry += fmt.Sprintf(",LANGUAGE=\"%s\"", rendition.Language)
+		}
+		entry += fmt.Sprintf(",DEFAULT=%s,AUTOSELECT=YES,CHANNELS=\"%d\",URI=\"%s\"\n",
+			yesNo(rendition.Default), rendition.Channels, variantURI(rendition.Name, audioPlaylists[i]))
+		f.WriteString(entry)
+		logger.AppLogger.Info("Added to master playlist",
+			zap.String("entry", entry),
+			zap.String("resolution", rendition.Name))
+	}
+
+	for i, track := range options.Subtitles {
+		entry := fmt.Sprintf("#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"%s\",NAME=\"%s\",LANGUAGE=\"%s\",DEFAULT=NO,AUTOSELECT=YES,FORCED=NO,URI=\"%s\"\n",
+			subtitleGroupId, track.Label, track.Language, variantURI(track.Name, subtitlePlaylists[i]))
+		f.WriteString(entry)
+		logger.AppLogger.Info("Added to master playlist",
+			zap.String("entry", entry),
+			zap.String("subtitle", track.Name))
+	}
+
+	mediaGroups := ""
+	if len(audio) > 0 {
+		mediaGroups = fmt.Sprintf(",AUDIO=\"%s\"", audioGroupId)
+	}
+	if len(options.Subtitles) > 0 {
+		mediaGroups += fmt.Sprintf(",SUBTITLES=\"%s\"", subtitleGroupId)
+	}
 
 	for i, playlist := range variantPlaylists {
 		if playlist == "" {
 			logger.AppLogger.Warn("Empty playlist", zap.Int("index", i))
 			continue
 		}
-		res := resolutions[i]
-		entry := fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/%s/%s\n",
-			getBandwidth(res), res.Width, res.Height, videoName, res.Name, playlist)
+		res := ladder[i]
+		// Muxed audio is already part of the measured segments
+		bandwidth, averageBandwidth := variantBandwidth(variantStats[i], audioStats)
+		codecs := res.Codecs
+		if hasAudio {
+			codecs += "," + audioCodecs
+		}
+		entry := fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,RESOLUTION=%dx%d,FRAME-RATE=%.3f,CODECS=\"%s\"%s\n%s\n",
+			bandwidth, averageBandwidth, res.OutputWidth, res.OutputHeight, variantStats[i].FrameRate, codecs, mediaGroups,
+			variantURI(res.Name, playlist))
+		f.WriteString(entry)
+		logger.AppLogger.Info("Added to master playlist",
+			zap.String("entry", entry),
+			zap.String("resolution", res.Name))
+	}
+
+	for i, iframe := range iframes {
+		if iframe.Name == "" {
+			continue
+		}
+		res := ladder[i]
+		entry := fmt.Sprintf("#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\",URI=\"%s\"\n",
+			iframe.Bandwidth, res.OutputWidth, res.OutputHeight, res.Codecs, variantURI(res.Name, iframe.Name))
 		f.WriteString(entry)
 		logger.AppLogger.Info("Added to master playlist",
 			zap.String("entry", entry),
 			zap.String("resolution", res.Name))
 	}
+
+	// Listen mode, players switch to it when the bandwidth cannot carry any video
+	if options.AudioOnlyVariant && len(audio) > 0 {
+		defaultAudio := 0
+		for i, rendition := range audio {
+			if rendition.Default {
+				defaultAudio = i
+			}
+		}
+		bandwidth, averageBandwidth := variantBandwidth(renditionStats{}, audioStats)
+		entry := fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,CODECS=\"%s\"%s\n%s\n",
+			bandwidth, averageBandwidth, audioCodecs, mediaGroups,
+			variantURI(audio[defaultAudio].Name, audioPlaylists[defaultAudio]))
+		f.WriteString(entry)
+		logger.AppLogger.Info("Added to master playlist", zap.String("entry", entry), zap.String("resolution", "audio_only"))
+	}
+}
+
+// variantURI is the URI of a rendition playlist in the master playlist, relative to the
+// master playlist so it resolves wherever the output is uploaded
+func variantURI(renditionName, playlistName string) string {
+	return fmt.Sprintf("%s/%s", renditionName, playlistName)
 }
 
-func getBandwidth(res Resolution) int {
-	switch res.Name {
-	case "1080p":
-		return 5000000
-	case "720p":
-		return 2800000
-	case "480p":
-		return 1400000
-	case "360p":
-		return 800000
-	default:
-		return 400000
+func yesNo(value bool) string {
+	if value {
+		return "YES"
 	}
+	return "NO"
 }
 
-func getVideoDuration(inputFile string) (time.Duration, error) {
-	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", inputFile)
+func getVideoDuration(ctx context.Context, inputFile string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", inputFile)
 	output, err := cmd.Output()
 	if err != nil {
 		return 0, err
@@ -217,35 +495,65 @@ func getVideoDuration(inputFile string) (time.Duration, error) {
 	return time.Duration(durationSec * float64(time.Second)), nil
 }
 
-func generateFFmpegCommand(inputFile, outputDir, playlistName string, res Resolution) (*exec.Cmd, error) {
-	outputPath := filepath.Join(outputDir, "segment_%03d.ts")
+// segmentFilePrefix is followed by the zero based segment number and the segment extension
+const segmentFilePrefix = "segment_"
+
+const masterPlaylistName = "master.m3u8"
+
+// audioCodecs is the RFC 6381 codecs string of the AAC-LC audio
+const audioCodecs = "mp4a.40.2"
+
+// generateFFmpegCommand encodes a video rendition, with the audio muxed in unless the audio
+// has renditions of its own
+func generateFFmpegCommand(ctx context.Context, inputFile, outputDir, playlistName string, res Resolution, segmentPackaging Packaging, muxAudio bool) (*exec.Cmd, error) {
+	outputPath := filepath.Join(outputDir, segmentFilePrefix+"%03d."+segmentPackaging.segmentExtension())
 	playlistPath := filepath.Join(outputDir, playlistName)
 
 	args := []string{
 		"-i", inputFile,
-		"-profile:v", "main",
-		"-level", "3.1",
 		"-start_number", "0",
 		"-hls_time", fmt.Sprintf("%d", res.SegmentDuration),
 		"-hls_list_size", "0",
 		"-f", "hls",
-		"-vf", fmt.Sprintf("scale=%d:%d", res.Width, res.Height),
-		"-c:a", "aac",
-		"-ar", "48000",
-		"-b:a", "128k",
+		"-vf", scaleFilter(res),
 		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", res.SegmentDuration),
 		"-hls_flags", "split_by_time+independent_segments",
-		"-hls_segment_type", "mpegts",
+		"-hls_segment_type", segmentPackaging.segmentType(),
 		"-hls_segment_filename", outputPath,
-		playlistPath,
 	}
+	args = append(args, encoderArgs(res)...)
+	if muxAudio {
+		args = append(args,
+			"-c:a", "aac",
+			"-ar", fmt.Sprintf("%d", audioSampleRate),
+			"-b:a", fmt.Sprintf("%dk", res.AudioBitrateKbps),
+		)
+	} else {
+		args = append(args, "-map", "0:v:0", "-an")
+	}
+	if segmentPackaging == PackagingFMP4 {
+		// The init segment lands next to the fragments and is referenced by EXT-X-MAP
+		args = append(args, "-hls_fmp4_init_filename", fmp4InitFilename)
+	}
+	args = append(args, playlistPath)
 
-	cmd := exec.Command("ffmpeg", args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 
 	return cmd, nil
 }
 
-func monitorProgress(stderr io.Reader, duration time.Duration, resName string) {
+// scaleFilter scales the picture to the size picked by fitLadderToSource. The sample aspect
+// ratio is reset because the size already accounts for anamorphic sources. FFmpeg applies the
+// rotation of the source before the filter runs.
+func scaleFilter(res Resolution) string {
+	filter := fmt.Sprintf("scale=%d:%d", res.ScaleWidth, res.ScaleHeight)
+	if res.OutputWidth != res.ScaleWidth || res.OutputHeight != res.ScaleHeight {
+		filter += fmt.Sprintf(",pad=%d:%d:(ow-iw)/2:(oh-ih)/2:black", res.OutputWidth, res.OutputHeight)
+	}
+	return filter + ",setsar=1"
+}
+
+func monitorProgress(stderr io.Reader, duration time.Duration, videoId, resName string) {
 	scanner := bufio.NewScanner(stderr)
 	re := regexp.MustCompile(`time=(\d{2}):(\d{2}):(\d{2})\.(\d{2})`)
 	for scanner.Scan() {
@@ -260,6 +568,7 @@ func monitorProgress(stderr io.Reader, duration time.Duration, resName string) {
 			logger.AppLogger.Info("Progress",
 				zap.String("resolution", resName),
 				zap.Float64("percentage", progress))
+			jobtracker.SetRenditionProgress(videoId, resName, progress)
 		}
 	}
 }
@@ -0,0 +1,286 @@
+package hlssegmenter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const tsPacketSize = 188
+
+// iframePlaylist is the I-frame only playlist written for a video rendition
+type iframePlaylist struct {
+	Name string
+	// Bandwidth is the peak bits per second of the I-frames alone
+	Bandwidth int
+}
+
+// generateIFramePlaylists writes an I-frame playlist for every video rendition. Keyframes are
+// forced at segment boundaries, so the I-frame of a segment is the byte range from the start
+// of the segment, with the container headers, to the end of its first keyframe.
+func generateIFramePlaylists(outputDir string, ladder []Resolution, variantPlaylists []string, segmentPackaging Packaging) ([]iframePlaylist, error) {
+	iframes := make([]iframePlaylist, len(variantPlaylists))
+
+	for i, playlistName := range variantPlaylists {
+		if playlistName == "" {
+			continue
+		}
+		res := ladder[i]
+		renditionDir := filepath.Join(outputDir, res.Name)
+
+		playlist, err := parseMediaPlaylist(filepath.Join(renditionDir, playlistName))
+		if err != nil {
+			return nil, fmt.Errorf("rendition %s: %v", res.Name, err)
+		}
+
+		var out bytes.Buffer
+		out.WriteString("#EXTM3U\n")
+		// Byte ranges need version 4
+		fmt.Fprintf(&out, "#EXT-X-VERSION:%d\n", max(4, segmentPackaging.playlistVersion()))
+		fmt.Fprintf(&out, "#EXT-X-TARGETDURATION:%d\n", playlist.TargetDuration)
+		out.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+		out.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+		out.WriteString("#EXT-X-I-FRAMES-ONLY\n")
+		if playlist.MapURI != "" {
+			fmt.Fprintf(&out, "#EXT-X-MAP:URI=\"%s\"\n", playlist.MapURI)
+		}
+
+		peak := 0
+		for _, segment := range playlist.Segments {
+			data, err := os.ReadFile(filepath.Join(renditionDir, segment.URI))
+			if err != nil {
+				return nil, fmt.Errorf("rendition %s: failed to read segment: %v", res.Name, err)
+			}
+
+			var length int
+			if segmentPackaging == PackagingFMP4 {
+				length, err = fmp4KeyframeEnd(data)
+			} else {
+				length, err = tsKeyframeEnd(data)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("rendition %s: segment %s: %v", res.Name, segment.URI, err)
+			}
+
+			if segment.Duration > 0 {
+				peak = max(peak, int(float64(length*8)/segment.Duration))
+			}
+			fmt.Fprintf(&out, "#EXTINF:%.6f,\n#EXT-X-BYTERANGE:%d@0\n%s\n", segment.Duration, length, segment.URI)
+		}
+		out.WriteString("#EXT-X-ENDLIST\n")
+
+		name := fmt.Sprintf("iframes_%s.m3u8", res.Name)
+		if err := os.WriteFile(filepath.Join(renditionDir, name), out.Bytes(), 0644); err != nil {
+			return nil, fmt.Errorf("rendition %s: failed to write I-frame playlist: %v", res.Name, err)
+		}
+		iframes[i] = iframePlaylist{Name: name, Bandwidth: peak}
+	}
+
+	return iframes, nil
+}
+
+// tsKeyframeEnd returns the offset of the TS packet that starts the second video PES packet,
+// the first one holds the keyframe the segment starts with
+func tsKeyframeEnd(data []byte) (int, error) {
+	pmtPid, videoPid := -1, -1
+	started := false
+
+	for offset := 0; offset+tsPacketSize <= len(data); offset += tsPacketSize {
+		packet := data[offset : offset+tsPacketSize]
+		if packet[0] != 0x47 {
+			return 0, fmt.Errorf("lost MPEG-TS sync at offset %d", offset)
+		}
+
+		pid := int(packet[1]&0x1f)<<8 | int(packet[2])
+		unitStart := packet[1]&0x40 != 0
+		adaptation := (packet[3] >> 4) & 0x3
+		if adaptation&0x1 == 0 {
+			continue
+		}
+		payload := packet[4:]
+		if adaptation&0x2 != 0 {
+			if int(packet[4])+1 >= len(payload) {
+				continue
+			}
+			payload = payload[int(packet[4])+1:]
+		}
+
+		switch {
+		case pid == 0 && unitStart && pmtPid < 0:
+			pmtPid = parsePAT(payload)
+		case pid == pmtPid && unitStart && videoPid < 0:
+			videoPid = parsePMTVideoPid(payload)
+		case pid == videoPid && unitStart:
+			if started {
+				return offset, nil
+			}
+			started = true
+		}
+	}
+
+	if !started {
+		return 0, fmt.Errorf("no video stream found")
+	}
+	// The whole segment is a single frame
+	return len(data) - len(data)%tsPacketSize, nil
+}
+
+// parsePAT returns the PMT PID of the first program
+func parsePAT(payload []byte) int {
+	section := psiSection(payload)
+	// 8 header bytes, then 4 bytes per program up to the 4 byte CRC
+	for i := 8; i+4 <= len(section)-4; i += 4 {
+		program := int(section[i])<<8 | int(section[i+1])
+		if program != 0 {
+			return int(section[i+2]&0x1f)<<8 | int(section[i+3])
+		}
+	}
+	return -1
+}
+
+// parsePMTVideoPid returns the PID of the first H.264, HEVC or MPEG-2 video stream
+func parsePMTVideoPid(payload []byte) int {
+	section := psiSection(payload)
+	if len(section) < 12 {
+		return -1
+	}
+	programInfoLength := int(section[10]&0x0f)<<8 | int(section[11])
+	for i := 12 + programInfoLength; i+5 <= len(section)-4; {
+		streamType := section[i]
+		pid := int(section[i+1]&0x1f)<<8 | int(section[i+2])
+		infoLength := int(section[i+3]&0x0f)<<8 | int(section[i+4])
+		switch streamType {
+		case 0x1b, 0x24, 0x02:
+			return pid
+		}
+		i += 5 + infoLength
+	}
+	return -1
+}
+
+// psiSection skips the pointer field and cuts the section to its declared length
+func psiSection(payload []byte) []byte {
+	if len(payload) == 0 || int(payload[0])+1 >= len(payload) {
+		return nil
+	}
+	section := payload[int(payload[0])+1:]
+	if len(section) < 3 {
+		return nil
+	}
+	length := int(section[1]&0x0f)<<8 | int(section[2])
+	if 3+length > len(section) {
+		return section
+	}
+	return section[:3+length]
+}
+
+// fmp4KeyframeEnd returns the offset right after the first sample of the first track
+// fragment, which is the keyframe the fragment starts with
+func fmp4KeyframeEnd(data []byte) (int, error) {
+	for offset := 0; offset+8 <= len(data); {
+		size, header := boxSize(data[offset:])
+		if size == 0 || offset+size > len(data) {
+			size = len(data) - offset
+		}
+		if string(data[offset+4:offset+8]) == "moof" {
+			return moofKeyframeEnd(data, offset, data[offset+header:offset+size])
+		}
+		offset += size
+	}
+	return 0, fmt.Errorf("no moof box found")
+}
+
+func moofKeyframeEnd(data []byte, moofOffset int, moof []byte) (int, error) {
+	for offset := 0; offset+8 <= len(moof); {
+		size, header := boxSize(moof[offset:])
+		if size < header || offset+size > len(moof) {
+			break
+		}
+		if string(moof[offset+4:offset+8]) == "traf" {
+			return trafKeyframeEnd(moofOffset, moof[offset+header:offset+size])
+		}
+		offset += size
+	}
+	return 0, fmt.Errorf("no traf box found")
+}
+
+func trafKeyframeEnd(moofOffset int, traf []byte) (int, error) {
+	base := int64(moofOffset)
+	defaultSampleSize := int64(0)
+	dataOffset := int64(0)
+	firstSampleSize := int64(-1)
+
+	for offset := 0; offset+8 <= len(traf); {
+		size, header := boxSize(traf[offset:])
+		if size < header || offset+size > len(traf) {
+			break
+		}
+		box := traf[offset+header : offset+size]
+
+		switch string(traf[offset+4 : offset+8]) {
+		case "tfhd":
+			if len(box) < 8 {
+				return 0, fmt.Errorf("truncated tfhd box")
+			}
+			flags := binary.BigEndian.Uint32(box[0:4]) & 0xffffff
+			position := 8
+			if flags&0x1 != 0 {
+				if position+8 > len(box) {
+					return 0, fmt.Errorf("truncated tfhd box")
+				}
+				base = int64(binary.BigEndian.Uint64(box[position:]))
+				position += 8
+			}
+			for _, flag := range []uint32{0x2, 0x8} {
+				if flags&flag != 0 {
+					position += 4
+				}
+			}
+			if flags&0x10 != 0 && position+4 <= len(box) {
+				defaultSampleSize = int64(binary.BigEndian.Uint32(box[position:]))
+			}
+		case "trun":
+			if len(box) < 8 {
+				return 0, fmt.Errorf("truncated trun box")
+			}
+			flags := binary.BigEndian.Uint32(box[0:4]) & 0xffffff
+			position := 8
+			if flags&0x1 != 0 {
+				if position+4 > len(box) {
+					return 0, fmt.Errorf("truncated trun box")
+				}
+				dataOffset = int64(int32(binary.BigEndian.Uint32(box[position:])))
+				position += 4
+			}
+			if flags&0x4 != 0 {
+				position += 4
+			}
+			if flags&0x100 != 0 {
+				position += 4
+			}
+			if flags&0x200 != 0 && position+4 <= len(box) {
+				firstSampleSize = int64(binary.BigEndian.Uint32(box[position:]))
+			}
+		}
+		offset += size
+	}
+
+	if firstSampleSize < 0 {
+		firstSampleSize = defaultSampleSize
+	}
+	if firstSampleSize <= 0 {
+		return 0, fmt.Errorf("no sample size in track fragment")
+	}
+	return int(base + dataOffset + firstSampleSize), nil
+}
+
+// boxSize returns the size of the ISO BMFF box at the start of data and the size of its header
+func boxSize(data []byte) (int, int) {
+	size := int(binary.BigEndian.Uint32(data[0:4]))
+	if size == 1 && len(data) >= 16 {
+		return int(binary.BigEndian.Uint64(data[8:16])), 16
+	}
+	return size, 8
+}
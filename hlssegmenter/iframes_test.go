@@ -0,0 +1,217 @@
+package hlssegmenter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// tsPacket builds a 188 byte MPEG-TS packet with the payload padded by stuffing bytes
+func tsPacket(pid int, unitStart bool, payload []byte) []byte {
+	packet := make([]byte, 4, tsPacketSize)
+	packet[0] = 0x47
+	packet[1] = byte(pid>>8) & 0x1f
+	if unitStart {
+		packet[1] |= 0x40
+	}
+	packet[2] = byte(pid)
+	packet[3] = 0x10
+	packet = append(packet, payload...)
+	return append(packet, bytes.Repeat([]byte{0xff}, tsPacketSize-len(packet))...)
+}
+
+// patPayload maps program 1 to the PMT PID
+func patPayload(pmtPid int) []byte {
+	return []byte{
+		0x00,             // pointer field
+		0x00, 0xb0, 0x0d, // table id, section length 13
+		0x00, 0x01, 0xc1, 0x00, 0x00, // transport stream id, version, section numbers
+		0x00, 0x01, 0xe0 | byte(pmtPid>>8), byte(pmtPid),
+		0x00, 0x00, 0x00, 0x00, // CRC
+	}
+}
+
+// pmtPayload lists a single elementary stream of the stream type
+func pmtPayload(streamType byte, pid int) []byte {
+	return []byte{
+		0x00,             // pointer field
+		0x02, 0xb0, 0x12, // table id, section length 18
+		0x00, 0x01, 0xc1, 0x00, 0x00, // program number, version, section numbers
+		0xe1, 0x00, // PCR PID
+		0xf0, 0x00, // program info length
+		streamType, 0xe0 | byte(pid>>8), byte(pid), 0xf0, 0x00,
+		0x00, 0x00, 0x00, 0x00, // CRC
+	}
+}
+
+func TestTSKeyframeEnd(t *testing.T) {
+	const pmtPid, videoPid, audioPid = 0x1000, 0x100, 0x101
+	pat := tsPacket(0, true, patPayload(pmtPid))
+	pmt := tsPacket(pmtPid, true, pmtPayload(0x1b, videoPid))
+	pes := []byte{0x00, 0x00, 0x01, 0xe0}
+
+	tests := []struct {
+		name    string
+		packets [][]byte
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "keyframe ends at the next video PES",
+			packets: [][]byte{
+				pat, pmt,
+				tsPacket(videoPid, true, pes),
+				tsPacket(videoPid, false, nil),
+				tsPacket(audioPid, true, nil),
+				tsPacket(videoPid, true, pes),
+				tsPacket(videoPid, false, nil),
+			},
+			want: 5 * tsPacketSize,
+		},
+		{
+			name: "segment of a single frame",
+			packets: [][]byte{
+				pat, pmt,
+				tsPacket(videoPid, true, pes),
+				tsPacket(videoPid, false, nil),
+			},
+			want: 4 * tsPacketSize,
+		},
+		{
+			name: "no video stream",
+			packets: [][]byte{
+				pat,
+				tsPacket(pmtPid, true, pmtPayload(0x0f, audioPid)),
+				tsPacket(audioPid, true, nil),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "lost sync",
+			packets: [][]byte{pat, append([]byte{0x00}, pmt[1:]...)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tsKeyframeEnd(bytes.Join(tt.packets, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tsKeyframeEnd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("tsKeyframeEnd() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// isoBox builds an ISO BMFF box of the type around the payloads
+func isoBox(boxType string, payloads ...[]byte) []byte {
+	payload := bytes.Join(payloads, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	box = append(box, boxType...)
+	return append(box, payload...)
+}
+
+// fullBoxFields encodes the version and flags of a full box followed by 32 bit fields
+func fullBoxFields(flags uint32, fields ...uint32) []byte {
+	data := binary.BigEndian.AppendUint32(nil, flags)
+	for _, field := range fields {
+		data = binary.BigEndian.AppendUint32(data, field)
+	}
+	return data
+}
+
+func TestFMP4KeyframeEnd(t *testing.T) {
+	// ftyp is 16 bytes, so the moof of the fragments below starts at offset 16
+	ftyp := isoBox("ftyp", []byte("iso6"), []byte{0, 0, 0, 0})
+	mfhd := isoBox("mfhd", fullBoxFields(0, 1))
+	mdat := isoBox("mdat", make([]byte, 64))
+
+	tests := []struct {
+		name    string
+		tfhd    []byte
+		trun    []byte
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "first sample size in trun",
+			// default-base-is-moof, track 1
+			tfhd: fullBoxFields(0x020000, 1),
+			// data offset and sample sizes: 2 samples, offset 100, sizes 40 and 10
+			trun: fullBoxFields(0x000201, 2, 100, 40, 10),
+			want: 16 + 100 + 40,
+		},
+		{
+			name: "default sample size in tfhd",
+			tfhd: fullBoxFields(0x020010, 1, 30),
+			trun: fullBoxFields(0x000001, 2, 100),
+			want: 16 + 100 + 30,
+		},
+		{
+			name: "explicit base data offset",
+			// base data offset 1000 as a 64 bit field, sample description index, default size 30
+			tfhd: fullBoxFields(0x000013, 1, 0, 1000, 1, 30),
+			trun: fullBoxFields(0x000001, 1, 8),
+			want: 1000 + 8 + 30,
+		},
+		{
+			name:    "no sample size",
+			tfhd:    fullBoxFields(0x020000, 1),
+			trun:    fullBoxFields(0x000001, 1, 100),
+			wantErr: true,
+		},
+		{
+			name:    "truncated tfhd",
+			tfhd:    fullBoxFields(0x000001, 1),
+			trun:    fullBoxFields(0x000201, 1, 100, 40),
+			wantErr: true,
+		},
+		{
+			name:    "truncated trun",
+			tfhd:    fullBoxFields(0x020000, 1),
+			trun:    fullBoxFields(0x000201, 1),
+			wantErr: true,
+		},
+		{
+			name:    "tfhd without track id",
+			tfhd:    fullBoxFields(0x020000),
+			trun:    fullBoxFields(0x000201, 1, 100, 40),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moof := isoBox("moof", mfhd, isoBox("traf", isoBox("tfhd", tt.tfhd), isoBox("trun", tt.trun)))
+			got, err := fmp4KeyframeEnd(bytes.Join([][]byte{ftyp, moof, mdat}, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fmp4KeyframeEnd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("fmp4KeyframeEnd() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFMP4KeyframeEndWithoutFragment(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "no moof", data: isoBox("ftyp", []byte("iso6"))},
+		{name: "no traf", data: isoBox("moof", isoBox("mfhd", fullBoxFields(0, 1)))},
+		{name: "empty", data: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := fmp4KeyframeEnd(tt.data); err == nil {
+				t.Error("fmp4KeyframeEnd() succeeded without a track fragment")
+			}
+		})
+	}
+}
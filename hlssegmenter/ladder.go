@@ -0,0 +1,479 @@
+package hlssegmenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"video_processor/logger"
+	"video_processor/messagemodel"
+	"video_processor/resolutionparser"
+
+	"go.uber.org/zap"
+)
+
+type Resolution struct {
+	Width            int
+	Height           int
+	Name             string
+	SegmentDuration  int // New field for segment duration
+	VideoBitrateKbps int
+	MaxrateKbps      int
+	BufsizeKbps      int
+	AudioBitrateKbps int
+	Codec            VideoCodec
+
+	// Set by assignCodecs from the output size: the encoder profile and level, and the
+	// RFC 6381 codecs string of the video
+	Profile string
+	Level   string
+	Codecs  string
+
+	// Set by fitLadderToSource: the size the source picture is scaled to, and the size of the
+	// encoded frame, which is larger than the picture when it is padded
+	ScaleWidth   int
+	ScaleHeight  int
+	OutputWidth  int
+	OutputHeight int
+}
+
+// ScaleMode controls how a source whose aspect ratio differs from a rung is fitted into it
+type ScaleMode string
+
+const (
+	// ScaleModeFit scales the picture into the rung and encodes it at that size
+	ScaleModeFit ScaleMode = "fit"
+	// ScaleModePad scales the picture into the rung and letterboxes it to the rung size
+	ScaleModePad ScaleMode = "pad"
+)
+
+// resolutions is the default rendition ladder, replaced by LoadRenditionLadder
+var resolutions = []Resolution{
+	{Width: 1920, Height: 1080, Name: "1080p", SegmentDuration: 2, VideoBitrateKbps: 5000, MaxrateKbps: 5350, BufsizeKbps: 7500, AudioBitrateKbps: 128, Codec: CodecH264},
+	{Width: 1280, Height: 720, Name: "720p", SegmentDuration: 3, VideoBitrateKbps: 2800, MaxrateKbps: 2996, BufsizeKbps: 4200, AudioBitrateKbps: 128, Codec: CodecH264},
+	{Width: 854, Height: 480, Name: "480p", SegmentDuration: 4, VideoBitrateKbps: 1400, MaxrateKbps: 1498, BufsizeKbps: 2100, AudioBitrateKbps: 128, Codec: CodecH264},
+	{Width: 640, Height: 360, Name: "360p", SegmentDuration: 5, VideoBitrateKbps: 800, MaxrateKbps: 856, BufsizeKbps: 1200, AudioBitrateKbps: 128, Codec: CodecH264},
+}
+
+// scaleMode is the default scale mode, replaced by LoadRenditionLadder
+var scaleMode = ScaleModeFit
+
+const (
+	defaultAudioBitrateKbps = 128
+	defaultSegmentDuration  = 4
+)
+
+// Rendition names become directory and file names of the output
+var renditionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type ladderConfig struct {
+	// Renditions is the ladder. Its highest rung caps the output: a source above it is only
+	// scaled down and gets no native rung, e.g. a 4K source tops out at a 1080p top rung.
+	// Add a higher rung to serve such sources at their resolution.
+	Renditions []messagemodel.Rendition `json:"renditions"`
+	ScaleMode  string                   `json:"scale_mode"`
+	Packaging  string                   `json:"packaging"`
+	Dash       bool                     `json:"dash"`
+	// Encrypt and KeyRotationSegments set the default AES-128 encryption
+	Encrypt             bool `json:"encrypt"`
+	KeyRotationSegments int  `json:"key_rotation_segments"`
+	// DemuxAudio and AudioOnlyVariant set the default audio layout
+	DemuxAudio       bool `json:"demux_audio"`
+	AudioOnlyVariant bool `json:"audio_only_variant"`
+	// Thumbnails and ThumbnailInterval set the default seek bar storyboard
+	Thumbnails        bool `json:"thumbnails"`
+	ThumbnailInterval int  `json:"thumbnail_interval"`
+	Poster            bool `json:"poster"`
+}
+
+// SegmentOptions are the settings a video is segmented with, from its request or the config
+type SegmentOptions struct {
+	Ladder    []Resolution
+	ScaleMode ScaleMode
+	Packaging Packaging
+	// DashManifest adds a manifest.mpd for the same renditions, it needs fmp4 packaging
+	DashManifest bool
+	// Encrypt encrypts the segments with AES-128, with a new key every KeyRotationSegments
+	// segments, or one key for the whole video when it is 0
+	Encrypt             bool
+	KeyRotationSegments int
+	// DemuxAudio encodes every audio track of the source as a rendition of its own instead of
+	// muxing the first one into each video rendition. AudioOnlyVariant also offers the audio
+	// without video for listen mode, it implies DemuxAudio, and so does DashManifest.
+	DemuxAudio       bool
+	AudioOnlyVariant bool
+	// Subtitles are converted to segmented WebVTT renditions
+	Subtitles []SubtitleTrack
+	// Thumbnails adds sprite sheets with a frame every ThumbnailInterval seconds and a
+	// thumbnails.vtt storyboard for seek bar previews
+	Thumbnails        bool
+	ThumbnailInterval int
+	// Poster picks a cover frame and writes it as JPEG and WebP at several sizes
+	Poster bool
+}
+
+// LoadRenditionLadder replaces the default ladder with the one in the JSON config file
+func LoadRenditionLadder(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.AppLogger.Error("Failed to read rendition ladder config", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to read rendition ladder config: %v", err)
+	}
+
+	var config ladderConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		logger.AppLogger.Error("Failed to parse rendition ladder config", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to parse rendition ladder config: %v", err)
+	}
+
+	ladder, err := toResolutions(config.Renditions)
+	if err != nil {
+		logger.AppLogger.Error("Invalid rendition ladder config", zap.Error(err), zap.String("path", path))
+		return err
+	}
+
+	mode := scaleMode
+	if config.ScaleMode != "" {
+		mode, err = toScaleMode(config.ScaleMode)
+		if err != nil {
+			logger.AppLogger.Error("Invalid scale mode in rendition ladder config", zap.Error(err), zap.String("path", path))
+			return err
+		}
+	}
+
+	segmentPackaging := packaging
+	if config.Packaging != "" {
+		segmentPackaging, err = toPackaging(config.Packaging)
+		if err != nil {
+			logger.AppLogger.Error("Invalid packaging in rendition ladder config", zap.Error(err), zap.String("path", path))
+			return err
+		}
+	}
+
+	if config.Dash && segmentPackaging != PackagingFMP4 {
+		err := fmt.Errorf("DASH manifest needs fmp4 packaging")
+		logger.AppLogger.Error("Invalid rendition ladder config", zap.Error(err), zap.String("path", path))
+		return err
+	}
+	if needsFMP4(ladder) && segmentPackaging != PackagingFMP4 {
+		err := fmt.Errorf("HEVC and AV1 renditions need fmp4 packaging")
+		logger.AppLogger.Error("Invalid rendition ladder config", zap.Error(err), zap.String("path", path))
+		return err
+	}
+	if err := validateEncryption(config.Encrypt, config.KeyRotationSegments, config.Dash); err != nil {
+		logger.AppLogger.Error("Invalid rendition ladder config", zap.Error(err), zap.String("path", path))
+		return err
+	}
+
+	resolutions = ladder
+	scaleMode = mode
+	packaging = segmentPackaging
+	dashManifest = config.Dash
+	encrypt = config.Encrypt
+	keyRotationSegments = config.KeyRotationSegments
+	demuxAudio = config.DemuxAudio || config.AudioOnlyVariant
+	audioOnlyVariant = config.AudioOnlyVariant
+	thumbnails = config.Thumbnails
+	poster = config.Poster
+	if config.ThumbnailInterval > 0 {
+		thumbnailInterval = config.ThumbnailInterval
+	}
+	logger.AppLogger.Info("Rendition ladder loaded",
+		zap.String("path", path),
+		zap.Int("renditions", len(ladder)),
+		zap.String("scaleMode", string(mode)),
+		zap.String("packaging", string(segmentPackaging)),
+		zap.Bool("dash", config.Dash),
+		zap.Bool("encrypt", config.Encrypt),
+		zap.Bool("demuxAudio", demuxAudio))
+	return nil
+}
+
+// ResolveOptions returns the segment options of a video and rejects invalid overrides
+func ResolveOptions(videoInfo messagemodel.VideoInfo) (SegmentOptions, error) {
+	ladder, err := ResolveLadder(videoInfo.Renditions)
+	if err != nil {
+		return SegmentOptions{}, err
+	}
+
+	mode := scaleMode
+	if videoInfo.ScaleMode != "" {
+		mode, err = toScaleMode(videoInfo.ScaleMode)
+		if err != nil {
+			return SegmentOptions{}, err
+		}
+	}
+
+	segmentPackaging := packaging
+	if videoInfo.Packaging != "" {
+		segmentPackaging, err = toPackaging(videoInfo.Packaging)
+		if err != nil {
+			return SegmentOptions{}, err
+		}
+	}
+
+	dash := dashManifest || videoInfo.Dash
+	if dash && segmentPackaging != PackagingFMP4 {
+		if videoInfo.Packaging != "" {
+			return SegmentOptions{}, fmt.Errorf("DASH manifest needs fmp4 packaging")
+		}
+		// DASH was asked for without a packaging, the configured TS default cannot serve it
+		segmentPackaging = PackagingFMP4
+	}
+	if needsFMP4(ladder) && segmentPackaging != PackagingFMP4 {
+		if videoInfo.Packaging != "" {
+			return SegmentOptions{}, fmt.Errorf("HEVC and AV1 renditions need fmp4 packaging")
+		}
+		segmentPackaging = PackagingFMP4
+	}
+
+	encryptSegments := encrypt || videoInfo.Encrypt
+	rotation := keyRotationSegments
+	if videoInfo.KeyRotationSegments != 0 {
+		rotation = videoInfo.KeyRotationSegments
+	}
+	if err := validateEncryption(encryptSegments, rotation, dash); err != nil {
+		return SegmentOptions{}, err
+	}
+
+	audioOnly := audioOnlyVariant || videoInfo.AudioOnlyVariant
+	// DASH players do not play representations with audio and video muxed together
+	demux := demuxAudio || videoInfo.DemuxAudio || audioOnly || dash
+
+	subtitles, err := subtitleTracks(videoInfo.Subtitles)
+	if err != nil {
+		return SegmentOptions{}, err
+	}
+
+	interval := thumbnailInterval
+	if videoInfo.ThumbnailInterval < 0 {
+		return SegmentOptions{}, fmt.Errorf("thumbnail interval must not be negative")
+	}
+	if videoInfo.ThumbnailInterval > 0 {
+		interval = videoInfo.ThumbnailInterval
+	}
+
+	return SegmentOptions{
+		Ladder:              ladder,
+		ScaleMode:           mode,
+		Packaging:           segmentPackaging,
+		DashManifest:        dash,
+		Encrypt:             encryptSegments,
+		KeyRotationSegments: rotation,
+		DemuxAudio:          demux,
+		AudioOnlyVariant:    audioOnly,
+		Subtitles:           subtitles,
+		Thumbnails:          thumbnails || videoInfo.Thumbnails,
+		ThumbnailInterval:   interval,
+		Poster:              poster || videoInfo.Poster,
+	}, nil
+}
+
+func validateEncryption(encrypt bool, rotateEvery int, dash bool) error {
+	if rotateEvery < 0 {
+		return fmt.Errorf("key rotation must not be negative")
+	}
+	// DASH players expect Common Encryption, they cannot read whole-segment AES-128
+	if encrypt && dash {
+		return fmt.Errorf("AES-128 encryption cannot be combined with a DASH manifest")
+	}
+	return nil
+}
+
+func needsFMP4(ladder []Resolution) bool {
+	for _, res := range ladder {
+		if res.Codec.needsFMP4() {
+			return true
+		}
+	}
+	return false
+}
+
+func toScaleMode(mode string) (ScaleMode, error) {
+	switch ScaleMode(mode) {
+	case ScaleModeFit, ScaleModePad:
+		return ScaleMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid scale mode %q", mode)
+}
+
+// ResolveLadder returns the ladder requested for a video, or the configured one when the request has none
+func ResolveLadder(renditions []messagemodel.Rendition) ([]Resolution, error) {
+	if len(renditions) == 0 {
+		return resolutions, nil
+	}
+	return toResolutions(renditions)
+}
+
+func toResolutions(renditions []messagemodel.Rendition) ([]Resolution, error) {
+	if len(renditions) == 0 {
+		return nil, fmt.Errorf("rendition ladder is empty")
+	}
+
+	ladder := make([]Resolution, 0, len(renditions))
+	names := make(map[string]bool, len(renditions))
+
+	for _, rendition := range renditions {
+		if !renditionNamePattern.MatchString(rendition.Name) {
+			return nil, fmt.Errorf("invalid rendition name %q", rendition.Name)
+		}
+		if names[rendition.Name] {
+			return nil, fmt.Errorf("duplicate rendition name %q", rendition.Name)
+		}
+		names[rendition.Name] = true
+
+		if rendition.Width <= 0 || rendition.Height <= 0 {
+			return nil, fmt.Errorf("rendition %s: width and height must be positive", rendition.Name)
+		}
+		if rendition.VideoBitrateKbps <= 0 {
+			return nil, fmt.Errorf("rendition %s: video bitrate must be positive", rendition.Name)
+		}
+
+		codec, err := toVideoCodec(rendition.Codec)
+		if err != nil {
+			return nil, fmt.Errorf("rendition %s: %v", rendition.Name, err)
+		}
+
+		res := Resolution{
+			Name:             rendition.Name,
+			Codec:            codec,
+			Width:            rendition.Width,
+			Height:           rendition.Height,
+			SegmentDuration:  rendition.SegmentDuration,
+			VideoBitrateKbps: rendition.VideoBitrateKbps,
+			MaxrateKbps:      rendition.MaxrateKbps,
+			BufsizeKbps:      rendition.BufsizeKbps,
+			AudioBitrateKbps: rendition.AudioBitrateKbps,
+		}
+
+		// Fill what the rung leaves out with the usual VBV ratios
+		if res.MaxrateKbps <= 0 {
+			res.MaxrateKbps = res.VideoBitrateKbps * 107 / 100
+		}
+		if res.BufsizeKbps <= 0 {
+			res.BufsizeKbps = res.VideoBitrateKbps * 3 / 2
+		}
+		if res.AudioBitrateKbps <= 0 {
+			res.AudioBitrateKbps = defaultAudioBitrateKbps
+		}
+		if res.SegmentDuration <= 0 {
+			res.SegmentDuration = defaultSegmentDuration
+		}
+
+		ladder = append(ladder, res)
+	}
+
+	return ladder, nil
+}
+
+// fitLadderToSource treats every rung as a bounding box and scales the source into it
+// keeping its aspect ratio. Rungs that would upscale the source are dropped. When rungs were
+// dropped and none of the remaining ones is at the source size, a rung at the native
+// resolution is added, derived from the lowest dropped rung. A source above the top rung gets
+// no native rung, the top rung is the highest resolution served.
+func fitLadderToSource(ladder []Resolution, source resolutionparser.VideoDimensions, mode ScaleMode) []Resolution {
+	var fitted []Resolution
+	var nearestAbove *Resolution
+	hasNative := false
+	// Encoders need even dimensions, a rung one pixel below an odd source is native too
+	nativeWidth, nativeHeight := evenFloor(source.Width), evenFloor(source.Height)
+	// Portrait sources fill the rung turned on its side, unless the frame is padded to the rung
+	portrait := mode == ScaleModeFit && source.Height > source.Width
+
+	for i := range ladder {
+		res := ladder[i]
+		boxWidth, boxHeight := res.Width, res.Height
+		if portrait {
+			boxWidth, boxHeight = boxHeight, boxWidth
+		}
+
+		scale := math.Min(float64(boxWidth)/float64(nativeWidth), float64(boxHeight)/float64(nativeHeight))
+		if scale > 1 {
+			if nearestAbove == nil || res.Height < nearestAbove.Height {
+				nearestAbove = &ladder[i]
+			}
+			logger.AppLogger.Info("Skipping rendition above source resolution",
+				zap.String("resolution", res.Name),
+				zap.Int("sourceWidth", source.Width),
+				zap.Int("sourceHeight", source.Height))
+			continue
+		}
+
+		res.ScaleWidth = min(evenRound(float64(nativeWidth)*scale), boxWidth)
+		res.ScaleHeight = min(evenRound(float64(nativeHeight)*scale), boxHeight)
+		res.OutputWidth, res.OutputHeight = res.ScaleWidth, res.ScaleHeight
+		if mode == ScaleModePad {
+			res.OutputWidth, res.OutputHeight = boxWidth, boxHeight
+		}
+
+		if res.ScaleWidth >= nativeWidth && res.ScaleHeight >= nativeHeight {
+			hasNative = true
+		}
+		fitted = append(fitted, res)
+	}
+
+	if nearestAbove == nil || hasNative {
+		return fitted
+	}
+
+	native := nativeRendition(*nearestAbove, nativeWidth, nativeHeight, fitted)
+	if mode == ScaleModePad {
+		// The native picture keeps its size, only the frame takes the shape of the ladder
+		boxAspect := float64(nearestAbove.Width) / float64(nearestAbove.Height)
+		if float64(nativeWidth)/float64(nativeHeight) > boxAspect {
+			native.OutputHeight = max(evenRound(float64(nativeWidth)/boxAspect), nativeHeight)
+		} else {
+			native.OutputWidth = max(evenRound(float64(nativeHeight)*boxAspect), nativeWidth)
+		}
+	}
+	logger.AppLogger.Info("Adding native resolution rendition",
+		zap.String("resolution", native.Name),
+		zap.Int("width", native.OutputWidth),
+		zap.Int("height", native.OutputHeight))
+
+	// Keep the ladder ordered from the highest rung down
+	for i, res := range fitted {
+		if res.ScaleWidth*res.ScaleHeight < native.ScaleWidth*native.ScaleHeight {
+			return append(fitted[:i], append([]Resolution{native}, fitted[i:]...)...)
+		}
+	}
+	return append(fitted, native)
+}
+
+func nativeRendition(above Resolution, width, height int, ladder []Resolution) Resolution {
+	// Bitrates follow the pixel count of the rung the native one replaces
+	scale := float64(width*height) / float64(above.Width*above.Height)
+
+	name := fmt.Sprintf("%dp", min(width, height))
+	for _, res := range ladder {
+		if res.Name == name {
+			name += "_native"
+			break
+		}
+	}
+
+	return Resolution{
+		Name:             name,
+		Width:            width,
+		Height:           height,
+		ScaleWidth:       width,
+		ScaleHeight:      height,
+		OutputWidth:      width,
+		OutputHeight:     height,
+		SegmentDuration:  above.SegmentDuration,
+		Codec:            above.Codec,
+		VideoBitrateKbps: int(float64(above.VideoBitrateKbps) * scale),
+		MaxrateKbps:      int(float64(above.MaxrateKbps) * scale),
+		BufsizeKbps:      int(float64(above.BufsizeKbps) * scale),
+		AudioBitrateKbps: above.AudioBitrateKbps,
+	}
+}
+
+func evenFloor(value int) int {
+	return value - value%2
+}
+
+func evenRound(value float64) int {
+	return int(math.Round(value/2)) * 2
+}
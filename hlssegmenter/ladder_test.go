@@ -0,0 +1,251 @@
+package hlssegmenter
+
+import (
+	"reflect"
+	"testing"
+	"video_processor/messagemodel"
+	"video_processor/resolutionparser"
+)
+
+// fittedRung is the part of a fitted rung the ladder tests compare
+type fittedRung struct {
+	Name                      string
+	ScaleWidth, ScaleHeight   int
+	OutputWidth, OutputHeight int
+}
+
+func TestFitLadderToSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source resolutionparser.VideoDimensions
+		mode   ScaleMode
+		want   []fittedRung
+	}{
+		{
+			name:   "source at the top rung",
+			source: resolutionparser.VideoDimensions{Width: 1920, Height: 1080},
+			mode:   ScaleModeFit,
+			want: []fittedRung{
+				{"1080p", 1920, 1080, 1920, 1080},
+				{"720p", 1280, 720, 1280, 720},
+				{"480p", 854, 480, 854, 480},
+				{"360p", 640, 360, 640, 360},
+			},
+		},
+		{
+			name:   "source above the top rung gets no native rung",
+			source: resolutionparser.VideoDimensions{Width: 3840, Height: 2160},
+			mode:   ScaleModeFit,
+			want: []fittedRung{
+				{"1080p", 1920, 1080, 1920, 1080},
+				{"720p", 1280, 720, 1280, 720},
+				{"480p", 854, 480, 854, 480},
+				{"360p", 640, 360, 640, 360},
+			},
+		},
+		{
+			name:   "source at a lower rung drops the rungs above",
+			source: resolutionparser.VideoDimensions{Width: 1280, Height: 720},
+			mode:   ScaleModeFit,
+			want: []fittedRung{
+				{"720p", 1280, 720, 1280, 720},
+				{"480p", 854, 480, 854, 480},
+				{"360p", 640, 360, 640, 360},
+			},
+		},
+		{
+			name:   "odd source one pixel above a rung",
+			source: resolutionparser.VideoDimensions{Width: 1281, Height: 721},
+			mode:   ScaleModeFit,
+			want: []fittedRung{
+				{"720p", 1280, 720, 1280, 720},
+				{"480p", 854, 480, 854, 480},
+				{"360p", 640, 360, 640, 360},
+			},
+		},
+		{
+			name:   "source between rungs adds a native rung",
+			source: resolutionparser.VideoDimensions{Width: 1000, Height: 562},
+			mode:   ScaleModeFit,
+			want: []fittedRung{
+				{"562p", 1000, 562, 1000, 562},
+				{"480p", 854, 480, 854, 480},
+				{"360p", 640, 360, 640, 360},
+			},
+		},
+		{
+			name:   "portrait source fills the rungs on their side",
+			source: resolutionparser.VideoDimensions{Width: 1080, Height: 1920},
+			mode:   ScaleModeFit,
+			want: []fittedRung{
+				{"1080p", 1080, 1920, 1080, 1920},
+				{"720p", 720, 1280, 720, 1280},
+				{"480p", 480, 854, 480, 854},
+				{"360p", 360, 640, 360, 640},
+			},
+		},
+		{
+			name:   "pad letterboxes the picture to the rung",
+			source: resolutionparser.VideoDimensions{Width: 1440, Height: 1080},
+			mode:   ScaleModePad,
+			want: []fittedRung{
+				{"1080p", 1440, 1080, 1920, 1080},
+				{"720p", 960, 720, 1280, 720},
+				{"480p", 640, 480, 854, 480},
+				{"360p", 480, 360, 640, 360},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ladder := append([]Resolution(nil), resolutions...)
+			var got []fittedRung
+			for _, res := range fitLadderToSource(ladder, tt.source, tt.mode) {
+				got = append(got, fittedRung{res.Name, res.ScaleWidth, res.ScaleHeight, res.OutputWidth, res.OutputHeight})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fitLadderToSource() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitLadderToSourceNativeBitrate(t *testing.T) {
+	ladder := append([]Resolution(nil), resolutions...)
+	fitted := fitLadderToSource(ladder, resolutionparser.VideoDimensions{Width: 1000, Height: 562}, ScaleModeFit)
+
+	native := fitted[0]
+	// 1000x562 is about 61% of the pixels of the 720p rung it replaces
+	if native.VideoBitrateKbps != 1707 || native.Codec != CodecH264 || native.SegmentDuration != 3 {
+		t.Errorf("native rung = %+v, want 1707 kbps h264 with 3s segments", native)
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		videoInfo     messagemodel.VideoInfo
+		wantPackaging Packaging
+		wantDash      bool
+		wantDemux     bool
+		wantErr       bool
+	}{
+		{
+			name:          "defaults",
+			wantPackaging: PackagingTS,
+		},
+		{
+			name:          "dash switches to fmp4 and demuxes the audio",
+			videoInfo:     messagemodel.VideoInfo{Dash: true},
+			wantPackaging: PackagingFMP4,
+			wantDash:      true,
+			wantDemux:     true,
+		},
+		{
+			name:          "audio only variant demuxes the audio",
+			videoInfo:     messagemodel.VideoInfo{AudioOnlyVariant: true},
+			wantPackaging: PackagingTS,
+			wantDemux:     true,
+		},
+		{
+			name: "hevc rung switches to fmp4",
+			videoInfo: messagemodel.VideoInfo{Renditions: []messagemodel.Rendition{
+				{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2000, Codec: "hevc"},
+			}},
+			wantPackaging: PackagingFMP4,
+		},
+		{
+			name:      "dash with ts packaging",
+			videoInfo: messagemodel.VideoInfo{Dash: true, Packaging: "ts"},
+			wantErr:   true,
+		},
+		{
+			name:      "dash with encryption",
+			videoInfo: messagemodel.VideoInfo{Dash: true, Encrypt: true},
+			wantErr:   true,
+		},
+		{
+			name:      "negative thumbnail interval",
+			videoInfo: messagemodel.VideoInfo{ThumbnailInterval: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid scale mode",
+			videoInfo: messagemodel.VideoInfo{ScaleMode: "stretch"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveOptions(tt.videoInfo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Packaging != tt.wantPackaging || got.DashManifest != tt.wantDash || got.DemuxAudio != tt.wantDemux {
+				t.Errorf("ResolveOptions() packaging = %s, dash = %v, demux = %v, want %s, %v, %v",
+					got.Packaging, got.DashManifest, got.DemuxAudio, tt.wantPackaging, tt.wantDash, tt.wantDemux)
+			}
+		})
+	}
+}
+
+func TestToResolutions(t *testing.T) {
+	tests := []struct {
+		name       string
+		renditions []messagemodel.Rendition
+		want       []Resolution
+		wantErr    bool
+	}{
+		{
+			name: "missing rates are derived from the bitrate",
+			renditions: []messagemodel.Rendition{
+				{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2000},
+			},
+			want: []Resolution{
+				{Name: "720p", Codec: CodecH264, Width: 1280, Height: 720, SegmentDuration: 4,
+					VideoBitrateKbps: 2000, MaxrateKbps: 2140, BufsizeKbps: 3000, AudioBitrateKbps: 128},
+			},
+		},
+		{name: "empty", wantErr: true},
+		{
+			name:       "name with a path separator",
+			renditions: []messagemodel.Rendition{{Name: "../720p", Width: 1280, Height: 720, VideoBitrateKbps: 2000}},
+			wantErr:    true,
+		},
+		{
+			name: "duplicate name",
+			renditions: []messagemodel.Rendition{
+				{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2000},
+				{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 1000},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "no bitrate",
+			renditions: []messagemodel.Rendition{{Name: "720p", Width: 1280, Height: 720}},
+			wantErr:    true,
+		},
+		{
+			name:       "unknown codec",
+			renditions: []messagemodel.Rendition{{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2000, Codec: "vp9"}},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toResolutions(tt.renditions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toResolutions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toResolutions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package hlssegmenter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"video_processor/resolutionparser"
+)
+
+// renditionStats is what a finished rendition actually takes, measured from its segments
+type renditionStats struct {
+	// PeakBandwidth is the bits per second of the largest segment for its duration
+	PeakBandwidth int
+	// AverageBandwidth is the bits per second over the whole rendition
+	AverageBandwidth int
+	// FrameRate is 0 for audio renditions
+	FrameRate float64
+}
+
+// measureRendition reads the segments of a rendition back from its playlist. encrypted
+// counts the AES-128 padding the segments get later, so the measure can run before the
+// segments are encrypted and the frame rate can still be probed.
+func measureRendition(ctx context.Context, renditionDir, playlistName string, video, encrypted bool) (renditionStats, error) {
+	playlist, err := parseMediaPlaylist(filepath.Join(renditionDir, playlistName))
+	if err != nil {
+		return renditionStats{}, err
+	}
+	if len(playlist.Segments) == 0 {
+		return renditionStats{}, fmt.Errorf("playlist %s has no segments", playlistName)
+	}
+
+	var stats renditionStats
+	var totalBits, totalDuration float64
+	for _, segment := range playlist.Segments {
+		info, err := os.Stat(filepath.Join(renditionDir, segment.URI))
+		if err != nil {
+			return renditionStats{}, fmt.Errorf("failed to stat segment: %v", err)
+		}
+		size := info.Size()
+		if encrypted {
+			// PKCS7 always pads to the next full block
+			size = (size/16 + 1) * 16
+		}
+
+		bits := float64(size * 8)
+		totalBits += bits
+		totalDuration += segment.Duration
+		if segment.Duration > 0 {
+			stats.PeakBandwidth = max(stats.PeakBandwidth, int(bits/segment.Duration))
+		}
+	}
+	if totalDuration > 0 {
+		stats.AverageBandwidth = int(totalBits / totalDuration)
+	}
+
+	if video {
+		stats.FrameRate, err = probeSegmentFrameRate(ctx, renditionDir, playlist)
+		if err != nil {
+			return renditionStats{}, err
+		}
+	}
+
+	return stats, nil
+}
+
+// probeSegmentFrameRate probes the first segment, behind its init segment for fMP4 since a
+// media segment alone carries no track description
+func probeSegmentFrameRate(ctx context.Context, renditionDir string, playlist mediaPlaylist) (float64, error) {
+	paths := []string{filepath.Join(renditionDir, playlist.Segments[0].URI)}
+	if playlist.MapURI != "" {
+		paths = append([]string{filepath.Join(renditionDir, playlist.MapURI)}, paths...)
+	}
+
+	readers := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open segment: %v", err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	return resolutionparser.GetStreamFrameRate(ctx, io.MultiReader(readers...))
+}
+
+// variantBandwidth adds the audio group to the measured video, the peak of the group counts
+// since a player may pick any of its renditions
+func variantBandwidth(video renditionStats, audio []renditionStats) (int, int) {
+	peak, average := video.PeakBandwidth, video.AverageBandwidth
+	audioPeak, audioAverage := 0, 0
+	for _, stats := range audio {
+		audioPeak = max(audioPeak, stats.PeakBandwidth)
+		audioAverage = max(audioAverage, stats.AverageBandwidth)
+	}
+	return peak + audioPeak, average + audioAverage
+}
@@ -0,0 +1,76 @@
+package hlssegmenter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mediaSegment is one segment listed in a variant playlist
+type mediaSegment struct {
+	Duration float64
+	URI      string
+}
+
+// mediaPlaylist is the part of a variant playlist written by FFmpeg that the segmenter reads back
+type mediaPlaylist struct {
+	TargetDuration int
+	MapURI         string
+	Segments       []mediaSegment
+}
+
+var mapURIPattern = regexp.MustCompile(`URI="([^"]*)"`)
+
+func parseMediaPlaylist(path string) (mediaPlaylist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return mediaPlaylist{}, fmt.Errorf("failed to open playlist: %v", err)
+	}
+	defer f.Close()
+
+	var playlist mediaPlaylist
+	var duration float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			playlist.TargetDuration, err = strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"))
+			if err != nil {
+				return mediaPlaylist{}, fmt.Errorf("invalid target duration %q: %v", line, err)
+			}
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if matches := mapURIPattern.FindStringSubmatch(line); len(matches) == 2 {
+				playlist.MapURI = matches[1]
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0]
+			duration, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return mediaPlaylist{}, fmt.Errorf("invalid segment duration %q: %v", line, err)
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			playlist.Segments = append(playlist.Segments, mediaSegment{Duration: duration, URI: line})
+			duration = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return mediaPlaylist{}, fmt.Errorf("failed to read playlist: %v", err)
+	}
+
+	return playlist, nil
+}
+
+// Duration is the total duration of the segments in seconds
+func (p mediaPlaylist) Duration() float64 {
+	var total float64
+	for _, segment := range p.Segments {
+		total += segment.Duration
+	}
+	return total
+}
@@ -0,0 +1,80 @@
+package hlssegmenter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMediaPlaylist(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		want     mediaPlaylist
+		wantErr  bool
+	}{
+		{
+			name: "ts",
+			playlist: "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:0\n" +
+				"#EXTINF:4.000000,\nsegment_000.ts\n#EXTINF:3.500000,\nsegment_001.ts\n#EXT-X-ENDLIST\n",
+			want: mediaPlaylist{
+				TargetDuration: 4,
+				Segments: []mediaSegment{
+					{Duration: 4, URI: "segment_000.ts"},
+					{Duration: 3.5, URI: "segment_001.ts"},
+				},
+			},
+		},
+		{
+			name: "fmp4 with an init segment",
+			playlist: "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-TARGETDURATION:2\n#EXT-X-MAP:URI=\"init.mp4\"\n\n" +
+				"#EXTINF:2.000000,\n#EXT-X-BYTERANGE:100@0\nsegment_000.m4s\n#EXT-X-ENDLIST\n",
+			want: mediaPlaylist{
+				TargetDuration: 2,
+				MapURI:         "init.mp4",
+				Segments:       []mediaSegment{{Duration: 2, URI: "segment_000.m4s"}},
+			},
+		},
+		{
+			name:     "invalid target duration",
+			playlist: "#EXTM3U\n#EXT-X-TARGETDURATION:four\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid segment duration",
+			playlist: "#EXTM3U\n#EXTINF:long,\nsegment_000.ts\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "playlist.m3u8")
+			if err := os.WriteFile(path, []byte(tt.playlist), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseMediaPlaylist(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMediaPlaylist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMediaPlaylist() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMediaPlaylistMissingFile(t *testing.T) {
+	if _, err := parseMediaPlaylist(filepath.Join(t.TempDir(), "missing.m3u8")); err == nil {
+		t.Error("parseMediaPlaylist() succeeded for a missing file")
+	}
+}
+
+func TestMediaPlaylistDuration(t *testing.T) {
+	playlist := mediaPlaylist{Segments: []mediaSegment{{Duration: 4}, {Duration: 4}, {Duration: 1.5}}}
+	if got := playlist.Duration(); got != 9.5 {
+		t.Errorf("Duration() = %v, want 9.5", got)
+	}
+}
@@ -0,0 +1,59 @@
+package hlssegmenter
+
+import "fmt"
+
+// Packaging is the container the HLS segments are written in
+type Packaging string
+
+const (
+	// PackagingTS writes MPEG-TS segments
+	PackagingTS Packaging = "ts"
+	// PackagingFMP4 writes CMAF segments, an init.mp4 with the track headers plus .m4s fragments
+	PackagingFMP4 Packaging = "fmp4"
+)
+
+const fmp4InitFilename = "init.mp4"
+
+// The packaging defaults, replaced by LoadRenditionLadder
+var (
+	packaging           = PackagingTS
+	dashManifest        = false
+	encrypt             = false
+	keyRotationSegments = 0
+	demuxAudio          = false
+	audioOnlyVariant    = false
+	thumbnails          = false
+	thumbnailInterval   = defaultThumbnailInterval
+	poster              = false
+)
+
+func toPackaging(value string) (Packaging, error) {
+	switch Packaging(value) {
+	case PackagingTS, PackagingFMP4:
+		return Packaging(value), nil
+	}
+	return "", fmt.Errorf("invalid packaging %q", value)
+}
+
+func (p Packaging) segmentType() string {
+	if p == PackagingFMP4 {
+		return "fmp4"
+	}
+	return "mpegts"
+}
+
+func (p Packaging) segmentExtension() string {
+	if p == PackagingFMP4 {
+		return "m4s"
+	}
+	return "ts"
+}
+
+// playlistVersion is the EXT-X-VERSION players need to read the playlists.
+// Version 7 is the first that allows EXT-X-MAP in playlists without I-frames only.
+func (p Packaging) playlistVersion() int {
+	if p == PackagingFMP4 {
+		return 7
+	}
+	return 3
+}
@@ -0,0 +1,227 @@
+package hlssegmenter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+	"video_processor/logger"
+	"video_processor/messagemodel"
+	"video_processor/resolutionparser"
+
+	"go.uber.org/zap"
+)
+
+const (
+	posterDirName = "poster"
+	// posterCandidates frames are scored, spread between 5% and 80% of the video where
+	// intros and end cards are unlikely
+	posterCandidates  = 12
+	posterSampleWidth = 160
+	// posterFadeOffset is how far apart the two frames compared for a fade or a cut are
+	posterFadeOffset = 500 * time.Millisecond
+
+	posterMinLuma     = 25
+	posterMaxLuma     = 235
+	posterMinEntropy  = 4.0
+	posterMaxFadeDiff = 20.0
+)
+
+// posterWidths are the sizes written, the first one is also written as poster.jpg and poster.webp
+var posterWidths = []int{1280, 640, 320}
+
+var posterFormats = []string{"jpg", "webp"}
+
+// posterQualityArgs are the encoder settings of a format, the scales differ: the MJPEG
+// -q:v runs from 2 (best) to 31, libwebp takes a 0-100 quality
+var posterQualityArgs = map[string][]string{
+	"jpg":  {"-q:v", "3"},
+	"webp": {"-quality", "80"},
+}
+
+// posterCandidate is a frame scored by how much detail it shows
+type posterCandidate struct {
+	At       time.Duration
+	Luma     float64
+	Entropy  float64
+	FadeDiff float64
+}
+
+func (c posterCandidate) usable() bool {
+	return c.Luma >= posterMinLuma && c.Luma <= posterMaxLuma &&
+		c.Entropy >= posterMinEntropy && c.FadeDiff <= posterMaxFadeDiff
+}
+
+// generatePoster picks a representative frame, skipping black and flat frames and frames in a
+// fade or cut, and writes it into outputDir/poster as JPEG and WebP at several widths
+func generatePoster(ctx context.Context, inputFile, outputDir string, source resolutionparser.VideoDimensions, duration time.Duration) ([]messagemodel.PosterImage, error) {
+	best, err := pickPosterFrame(ctx, inputFile, duration)
+	if err != nil {
+		return nil, err
+	}
+	logger.AppLogger.Info("Poster frame selected",
+		zap.Duration("at", best.At),
+		zap.Float64("luma", best.Luma),
+		zap.Float64("entropy", best.Entropy),
+		zap.Bool("usable", best.usable()))
+
+	posterDir := filepath.Join(outputDir, posterDirName)
+	if err := os.MkdirAll(posterDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create poster directory: %v", err)
+	}
+
+	var posters []messagemodel.PosterImage
+	for i, width := range posterWidths {
+		// Never upscale, the smaller sizes are still written for a small source
+		width = min(width, evenFloor(source.Width))
+		height := evenRound(float64(width) * float64(source.Height) / float64(source.Width))
+
+		for _, format := range posterFormats {
+			name := fmt.Sprintf("poster_%d.%s", posterWidths[i], format)
+			if i == 0 {
+				name = "poster." + format
+			}
+			path := filepath.Join(posterDir, name)
+
+			args := []string{
+				"-ss", fmt.Sprintf("%.3f", best.At.Seconds()),
+				"-i", inputFile,
+				"-frames:v", "1",
+				"-vf", fmt.Sprintf("scale=%d:%d,setsar=1", width, height),
+			}
+			args = append(args, posterQualityArgs[format]...)
+			args = append(args, "-y", path)
+			cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				logger.AppLogger.Error("FFmpeg poster extraction failed",
+					zap.Error(err),
+					zap.String("path", path),
+					zap.String("output", string(output)))
+				return nil, fmt.Errorf("poster %s: %v", name, err)
+			}
+
+			posters = append(posters, messagemodel.PosterImage{
+				Key:    filepath.ToSlash(filepath.Join(posterDirName, name)),
+				Format: format,
+				Width:  width,
+				Height: height,
+			})
+		}
+	}
+
+	return posters, nil
+}
+
+// pickPosterFrame returns the usable candidate with the most detail, or the most detailed
+// one when every candidate is black, flat or fading
+func pickPosterFrame(ctx context.Context, inputFile string, duration time.Duration) (posterCandidate, error) {
+	var best posterCandidate
+	bestScore := math.Inf(-1)
+
+	for i := 0; i < posterCandidates; i++ {
+		at := time.Duration(float64(duration) * (0.05 + 0.75*float64(i)/float64(posterCandidates-1)))
+
+		frame, err := extractGrayFrame(ctx, inputFile, at)
+		if err != nil {
+			return posterCandidate{}, err
+		}
+		next, err := extractGrayFrame(ctx, inputFile, at+posterFadeOffset)
+		if err != nil {
+			// The second frame can fall past the end of the video
+			next = frame
+		}
+
+		candidate := posterCandidate{At: at, FadeDiff: meanAbsDiff(frame, next)}
+		candidate.Luma, candidate.Entropy = lumaStats(frame)
+
+		score := candidate.Entropy
+		if candidate.usable() {
+			score += 100
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+
+	return best, nil
+}
+
+func extractGrayFrame(ctx context.Context, inputFile string, at time.Duration) (*image.Gray, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-ss", fmt.Sprintf("%.3f", at.Seconds()),
+		"-i", inputFile,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2,format=gray", posterSampleWidth),
+		"-f", "image2pipe",
+		"-c:v", "png",
+		"-",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract frame at %s: %v", at, err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame at %s: %v", at, err)
+	}
+	if gray, ok := img.(*image.Gray); ok {
+		return gray, nil
+	}
+
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray.Set(x, y, color.GrayModel.Convert(img.At(x, y)))
+		}
+	}
+	return gray, nil
+}
+
+// lumaStats returns the mean luma and the Shannon entropy of the luma histogram in bits
+func lumaStats(img *image.Gray) (float64, float64) {
+	var histogram [256]int
+	var sum int
+	for _, value := range img.Pix {
+		histogram[value]++
+		sum += int(value)
+	}
+	if len(img.Pix) == 0 {
+		return 0, 0
+	}
+
+	total := float64(len(img.Pix))
+	entropy := 0.0
+	for _, count := range histogram {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / total
+		entropy -= p * math.Log2(p)
+	}
+	return float64(sum) / total, entropy
+}
+
+// meanAbsDiff is high when the picture changes quickly, as in a fade or across a cut
+func meanAbsDiff(a, b *image.Gray) float64 {
+	if len(a.Pix) != len(b.Pix) || len(a.Pix) == 0 {
+		return 0
+	}
+	var diff int
+	for i := range a.Pix {
+		d := int(a.Pix[i]) - int(b.Pix[i])
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+	}
+	return float64(diff) / float64(len(a.Pix))
+}
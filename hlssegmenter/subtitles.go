@@ -0,0 +1,235 @@
+package hlssegmenter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"video_processor/messagemodel"
+)
+
+const (
+	// subtitleGroupId is the GROUP-ID of the subtitle renditions in the master playlist
+	subtitleGroupId = "subs"
+	// subtitleSegmentDuration is the length of a WebVTT segment in seconds
+	subtitleSegmentDuration = 10
+	// tsTimestampOffset is where FFmpeg starts the MPEG-TS timestamps, 1.4s in 90kHz ticks.
+	// WebVTT segments map their local time zero to it so the cues line up with the video.
+	tsTimestampOffset = 126000
+)
+
+// SubtitleTrack is a subtitle rendition of the output
+type SubtitleTrack struct {
+	Name     string
+	Language string
+	Label    string
+	S3Key    string
+	// LocalPath is set once the source file is downloaded
+	LocalPath string
+}
+
+// subtitleCue is one caption with the time range it is shown in
+type subtitleCue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+var cueTimingPattern = regexp.MustCompile(`^((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})\s+-->\s+((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})`)
+
+// subtitleTracks validates the subtitles of a request and names their output directories
+func subtitleTracks(subtitles []messagemodel.Subtitle) ([]SubtitleTrack, error) {
+	tracks := make([]SubtitleTrack, 0, len(subtitles))
+	names := make(map[string]bool, len(subtitles))
+	labels := make(map[string]bool, len(subtitles))
+
+	for i, subtitle := range subtitles {
+		if subtitle.S3Key == "" {
+			return nil, fmt.Errorf("subtitle %d: s3key is empty", i)
+		}
+		ext := strings.ToLower(filepath.Ext(subtitle.S3Key))
+		if ext != ".srt" && ext != ".vtt" {
+			return nil, fmt.Errorf("subtitle %d: unsupported format %q, expected .srt or .vtt", i, ext)
+		}
+		if !languagePattern.MatchString(subtitle.Language) {
+			return nil, fmt.Errorf("subtitle %d: invalid language %q", i, subtitle.Language)
+		}
+
+		name := "subtitles_" + subtitle.Language
+		if names[name] {
+			name = fmt.Sprintf("subtitles_%d", i)
+		}
+		names[name] = true
+
+		label := subtitle.Name
+		if label == "" {
+			label = subtitle.Language
+		}
+		if labels[label] {
+			return nil, fmt.Errorf("subtitle %d: duplicate name %q", i, label)
+		}
+		labels[label] = true
+
+		tracks = append(tracks, SubtitleTrack{
+			Name:     name,
+			Language: subtitle.Language,
+			Label:    label,
+			S3Key:    subtitle.S3Key,
+		})
+	}
+
+	return tracks, nil
+}
+
+// segmentSubtitles converts the subtitle file of a track to segmented WebVTT with a media
+// playlist in outputDir/<track name>, covering the whole duration of the video
+func segmentSubtitles(track SubtitleTrack, outputDir string, duration time.Duration, segmentPackaging Packaging) (string, error) {
+	data, err := os.ReadFile(track.LocalPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read subtitle file: %v", err)
+	}
+
+	cues, err := parseSubtitles(data)
+	if err != nil {
+		return "", err
+	}
+
+	trackDir := filepath.Join(outputDir, track.Name)
+	if err := os.MkdirAll(trackDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create subtitle directory: %v", err)
+	}
+
+	timestampOffset := 0
+	if segmentPackaging == PackagingTS {
+		timestampOffset = tsTimestampOffset
+	}
+
+	segmentLength := subtitleSegmentDuration * time.Second
+	segmentCount := max(int(math.Ceil(float64(duration)/float64(segmentLength))), 1)
+
+	var playlist bytes.Buffer
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&playlist, "#EXT-X-TARGETDURATION:%d\n", subtitleSegmentDuration)
+	playlist.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	playlist.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+
+	for i := 0; i < segmentCount; i++ {
+		start := time.Duration(i) * segmentLength
+		end := min(start+segmentLength, duration)
+
+		var segment bytes.Buffer
+		segment.WriteString("WEBVTT\n")
+		fmt.Fprintf(&segment, "X-TIMESTAMP-MAP=MPEGTS:%d,LOCAL:00:00:00.000\n", timestampOffset)
+		for _, cue := range cues {
+			// A cue spanning a segment boundary is repeated in every segment it overlaps
+			if cue.End <= start || cue.Start >= end {
+				continue
+			}
+			fmt.Fprintf(&segment, "\n%s --> %s\n%s\n", formatVTTTimestamp(cue.Start), formatVTTTimestamp(cue.End), cue.Text)
+		}
+
+		segmentName := fmt.Sprintf("%s%03d.vtt", segmentFilePrefix, i)
+		if err := os.WriteFile(filepath.Join(trackDir, segmentName), segment.Bytes(), 0644); err != nil {
+			return "", fmt.Errorf("failed to write subtitle segment: %v", err)
+		}
+		fmt.Fprintf(&playlist, "#EXTINF:%.6f,\n%s\n", (end - start).Seconds(), segmentName)
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+
+	playlistName := fmt.Sprintf("playlist_%s.m3u8", track.Name)
+	if err := os.WriteFile(filepath.Join(trackDir, playlistName), playlist.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write subtitle playlist: %v", err)
+	}
+
+	return playlistName, nil
+}
+
+// parseSubtitles reads the cues of an SRT or WebVTT file. Cue settings, WebVTT NOTE, STYLE
+// and REGION blocks are dropped, the cue text is kept as is.
+func parseSubtitles(data []byte) ([]subtitleCue, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	var cues []subtitleCue
+	var block []string
+	flush := func() error {
+		defer func() { block = block[:0] }()
+		for i, line := range block {
+			matches := cueTimingPattern.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+			start, err := parseSubtitleTimestamp(matches[1])
+			if err != nil {
+				return err
+			}
+			end, err := parseSubtitleTimestamp(matches[2])
+			if err != nil {
+				return err
+			}
+			text := strings.TrimSpace(strings.Join(block[i+1:], "\n"))
+			if text != "" && end > start {
+				cues = append(cues, subtitleCue{Start: start, End: end, Text: text})
+			}
+			return nil
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read subtitle file: %v", err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("subtitle file has no cues")
+	}
+	return cues, nil
+}
+
+func parseSubtitleTimestamp(value string) (time.Duration, error) {
+	value = strings.Replace(value, ",", ".", 1)
+	parts := strings.Split(value, ":")
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subtitle timestamp %q", value)
+	}
+	total := time.Duration(seconds * float64(time.Second))
+
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid subtitle timestamp %q", value)
+		}
+		total += time.Duration(n) * unit
+		unit *= 60
+	}
+	return total, nil
+}
+
+func formatVTTTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
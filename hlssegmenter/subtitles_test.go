@@ -0,0 +1,104 @@
+package hlssegmenter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSubtitles(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []subtitleCue
+		wantErr bool
+	}{
+		{
+			name: "srt",
+			data: "1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nTwo\r\nlines\r\n",
+			want: []subtitleCue{
+				{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello"},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "Two\nlines"},
+			},
+		},
+		{
+			name: "vtt with settings, notes and a byte order mark",
+			data: "\xef\xbb\xbfWEBVTT\n\nNOTE written by hand\n\nSTYLE\n::cue { color: red }\n\nintro\n00:01.000 --> 00:02.000 align:start\n<i>Hi</i>\n\n01:00:00.000 --> 01:00:01.250\nLate\n",
+			want: []subtitleCue{
+				{Start: time.Second, End: 2 * time.Second, Text: "<i>Hi</i>"},
+				{Start: time.Hour, End: time.Hour + 1250*time.Millisecond, Text: "Late"},
+			},
+		},
+		{
+			name: "cues without text or with the end before the start are dropped",
+			data: "1\n00:00:01,000 --> 00:00:02,000\n\n2\n00:00:05,000 --> 00:00:04,000\nBackwards\n\n3\n00:00:06,000 --> 00:00:07,000\nKept\n",
+			want: []subtitleCue{
+				{Start: 6 * time.Second, End: 7 * time.Second, Text: "Kept"},
+			},
+		},
+		{
+			name:    "no cues",
+			data:    "WEBVTT\n\nNOTE nothing here\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSubtitles([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSubtitles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSubtitles() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSubtitleTimestamp(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "00:00:01,500", want: 1500 * time.Millisecond},
+		{value: "01:02:03.004", want: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond},
+		{value: "02:03.500", want: 2*time.Minute + 3500*time.Millisecond},
+		{value: "100:00:00.000", want: 100 * time.Hour},
+		{value: "00:xx:01.000", wantErr: true},
+		{value: "00:00:ab", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSubtitleTimestamp(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSubtitleTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSubtitleTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatVTTTimestamp(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "00:00:00.000"},
+		{d: 1500 * time.Millisecond, want: "00:00:01.500"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, want: "01:02:03.004"},
+		{d: 100 * time.Hour, want: "100:00:00.000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatVTTTimestamp(tt.d); got != tt.want {
+				t.Errorf("formatVTTTimestamp() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package hlssegmenter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+	"video_processor/logger"
+	"video_processor/resolutionparser"
+	"video_processor/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	thumbnailsDirName  = "thumbnails"
+	thumbnailsVTTName  = "thumbnails.vtt"
+	thumbnailSheetName = "sprite_%03d.jpg"
+	thumbnailWidth     = 160
+	// Every sprite sheet is a grid of thumbnailColumns x thumbnailRows frames
+	thumbnailColumns = 10
+	thumbnailRows    = 10
+	// defaultThumbnailInterval is the seconds between two frames when the request sets none
+	defaultThumbnailInterval = 5
+)
+
+// showinfoFramePattern matches the line the showinfo filter logs for every frame
+var showinfoFramePattern = regexp.MustCompile(`(?m)\[Parsed_showinfo_\d+ @ [^\]]+\] n:\s*\d+ `)
+
+// generateThumbnails writes JPEG sprite sheets with a frame every interval seconds into
+// outputDir/thumbnails, plus a WebVTT storyboard that maps time ranges to the frames
+func generateThumbnails(ctx context.Context, inputFile, outputDir string, source resolutionparser.VideoDimensions, duration time.Duration, interval int) error {
+	thumbnailsDir := filepath.Join(outputDir, thumbnailsDirName)
+	if err := os.MkdirAll(thumbnailsDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create thumbnails directory: %v", err)
+	}
+	// A retried run must not leave sheets of the previous attempt behind
+	if err := utils.DeleteDirContents(thumbnailsDir); err != nil {
+		return err
+	}
+
+	width := min(thumbnailWidth, evenFloor(source.Width))
+	height := evenRound(float64(width) * float64(source.Height) / float64(source.Width))
+
+	args := []string{
+		"-i", inputFile,
+		"-map", "0:v:0",
+		"-an", "-sn",
+		// showinfo logs every frame that goes into the sheets, the storyboard has a cue for each
+		"-vf", fmt.Sprintf("fps=1/%d,scale=%d:%d,setsar=1,showinfo,tile=%dx%d", interval, width, height, thumbnailColumns, thumbnailRows),
+		"-q:v", "4",
+		"-start_number", "0",
+		filepath.Join(thumbnailsDir, thumbnailSheetName),
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		logger.AppLogger.Error("FFmpeg thumbnail extraction failed",
+			zap.Error(err),
+			zap.String("output", string(output)))
+		return fmt.Errorf("thumbnail extraction failed: %v", err)
+	}
+
+	// The fps filter rounds timestamps, so the frame count is not simply duration / interval
+	frames := len(showinfoFramePattern.FindAll(output, -1))
+	if frames == 0 {
+		return fmt.Errorf("thumbnail extraction wrote no frames")
+	}
+	if err := writeThumbnailsVTT(filepath.Join(thumbnailsDir, thumbnailsVTTName), frames, width, height, interval, duration); err != nil {
+		return err
+	}
+
+	logger.AppLogger.Info("Thumbnails generated",
+		zap.String("dir", thumbnailsDir),
+		zap.Int("frames", frames),
+		zap.Int("interval", interval))
+	return nil
+}
+
+func writeThumbnailsVTT(path string, frames, width, height, interval int, duration time.Duration) error {
+	framesPerSheet := thumbnailColumns * thumbnailRows
+
+	var vtt bytes.Buffer
+	vtt.WriteString("WEBVTT\n")
+	for frame := 0; frame < frames; frame++ {
+		start := time.Duration(frame*interval) * time.Second
+		end := min(start+time.Duration(interval)*time.Second, duration)
+		if frame == frames-1 && end < duration {
+			end = duration
+		}
+		if end <= start {
+			end = start + time.Duration(interval)*time.Second
+		}
+
+		position := frame % framesPerSheet
+		x := position % thumbnailColumns * width
+		y := position / thumbnailColumns * height
+		sheet := fmt.Sprintf(thumbnailSheetName, frame/framesPerSheet)
+
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end), sheet, x, y, width, height)
+	}
+
+	if err := os.WriteFile(path, vtt.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write thumbnails storyboard: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package hlssegmenter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteThumbnailsVTT(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   int
+		interval int
+		duration time.Duration
+		// wantCues holds the timing line and the image of every cue
+		wantCues [][2]string
+	}{
+		{
+			name:     "duration a multiple of the interval",
+			frames:   2,
+			interval: 5,
+			duration: 10 * time.Second,
+			wantCues: [][2]string{
+				{"00:00:00.000 --> 00:00:05.000", "sprite_000.jpg#xywh=0,0,160,90"},
+				{"00:00:05.000 --> 00:00:10.000", "sprite_000.jpg#xywh=160,0,160,90"},
+			},
+		},
+		{
+			name:     "last cue runs to the end of the video",
+			frames:   2,
+			interval: 5,
+			duration: 12500 * time.Millisecond,
+			wantCues: [][2]string{
+				{"00:00:00.000 --> 00:00:05.000", "sprite_000.jpg#xywh=0,0,160,90"},
+				{"00:00:05.000 --> 00:00:12.500", "sprite_000.jpg#xywh=160,0,160,90"},
+			},
+		},
+		{
+			name:     "frame after the end of the video keeps a cue",
+			frames:   3,
+			interval: 5,
+			duration: 10 * time.Second,
+			wantCues: [][2]string{
+				{"00:00:00.000 --> 00:00:05.000", "sprite_000.jpg#xywh=0,0,160,90"},
+				{"00:00:05.000 --> 00:00:10.000", "sprite_000.jpg#xywh=160,0,160,90"},
+				{"00:00:10.000 --> 00:00:15.000", "sprite_000.jpg#xywh=320,0,160,90"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), thumbnailsVTTName)
+			if err := writeThumbnailsVTT(path, tt.frames, 160, 90, tt.interval, tt.duration); err != nil {
+				t.Fatalf("writeThumbnailsVTT() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks := strings.Split(strings.TrimSpace(string(data)), "\n\n")
+			if blocks[0] != "WEBVTT" {
+				t.Fatalf("storyboard starts with %q, want WEBVTT", blocks[0])
+			}
+			if len(blocks)-1 != len(tt.wantCues) {
+				t.Fatalf("storyboard has %d cues, want %d", len(blocks)-1, len(tt.wantCues))
+			}
+			for i, want := range tt.wantCues {
+				if got := blocks[i+1]; got != want[0]+"\n"+want[1] {
+					t.Errorf("cue %d = %q, want %q", i, got, want[0]+"\n"+want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteThumbnailsVTTSheets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), thumbnailsVTTName)
+	frames := thumbnailColumns*thumbnailRows + 1
+	if err := writeThumbnailsVTT(path, frames, 160, 90, 1, time.Duration(frames)*time.Second); err != nil {
+		t.Fatalf("writeThumbnailsVTT() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []string{
+		// Last frame of the first sheet, bottom right
+		"00:01:39.000 --> 00:01:40.000\nsprite_000.jpg#xywh=1440,810,160,90",
+		// The next frame starts the second sheet
+		"00:01:40.000 --> 00:01:41.000\nsprite_001.jpg#xywh=0,0,160,90",
+	}
+	for _, cue := range tests {
+		if !strings.Contains(string(data), cue) {
+			t.Errorf("storyboard has no cue %q", cue)
+		}
+	}
+}
+
+func TestShowinfoFramePattern(t *testing.T) {
+	output := strings.Join([]string{
+		"Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'source.mp4':",
+		"[Parsed_showinfo_3 @ 0x55d0c8a4e2c0] config in time_base: 1/5, frame_rate: 1/5",
+		"[Parsed_showinfo_3 @ 0x55d0c8a4e2c0] n:   0 pts:      0 pts_time:0       duration:      1",
+		"[Parsed_showinfo_3 @ 0x55d0c8a4e2c0]   color_range:tv color_space:bt709",
+		"[Parsed_showinfo_3 @ 0x55d0c8a4e2c0] n:   1 pts:      1 pts_time:5       duration:      1",
+		"[Parsed_showinfo_3 @ 0x55d0c8a4e2c0] n:  12 pts:     12 pts_time:60      duration:      1",
+		"frame=    1 fps=0.0 q=4.0 Lsize=N/A time=00:01:05.00 bitrate=N/A speed= 120x",
+	}, "\n")
+
+	if got := len(showinfoFramePattern.FindAll([]byte(output), -1)); got != 3 {
+		t.Errorf("showinfoFramePattern matched %d frames, want 3", got)
+	}
+}
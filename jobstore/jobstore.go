@@ -0,0 +1,231 @@
+package jobstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"video_processor/logger"
+	"video_processor/messagemodel"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var jobsBucket = []byte("jobs")
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is the durable record of a video processing job
+type Job struct {
+	VideoInfo messagemodel.VideoInfo `json:"video_info"`
+	Stage     string                 `json:"stage"`
+	Finished  bool                   `json:"finished"`
+	Attempts  int                    `json:"attempts"`
+	Errors    []string               `json:"errors"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+
+	// SourceETag identifies the content of the source object of the latest run
+	SourceETag string `json:"source_etag"`
+	// CompletedSourceETag is the SourceETag of the latest run that finished successfully
+	CompletedSourceETag string `json:"completed_source_etag"`
+	// ProcessedSegments is the output of the latest run once segmenting finished, an
+	// interrupted upload resumes from it
+	ProcessedSegments *messagemodel.ProcessedSegmentsInfo `json:"processed_segments,omitempty"`
+	// UploadedSegments is where the output of the latest successful run landed, it is kept
+	// until another run of the video finishes
+	UploadedSegments *messagemodel.SegmentsUploadedInfo `json:"uploaded_segments,omitempty"`
+}
+
+var db *bolt.DB
+
+func Open(path string) error {
+	var err error
+	db, err = bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		logger.AppLogger.Error("Failed to open job store", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to open job store: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to create jobs bucket", zap.Error(err))
+		return fmt.Errorf("failed to create jobs bucket: %v", err)
+	}
+
+	logger.AppLogger.Info("Job store opened", zap.String("path", path))
+	return nil
+}
+
+func Close() error {
+	if db == nil {
+		return nil
+	}
+	return db.Close()
+}
+
+// update loads the job, lets fn modify it and writes it back in one transaction
+func update(videoId string, fn func(job *Job)) error {
+	if db == nil {
+		return nil
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobsBucket)
+
+		job := Job{CreatedAt: time.Now()}
+		if data := bucket.Get([]byte(videoId)); data != nil {
+			if err := json.Unmarshal(data, &job); err != nil {
+				return fmt.Errorf("cannot unmarshal job %s: %v", videoId, err)
+			}
+		}
+		job.VideoInfo.VideoId = videoId
+
+		fn(&job)
+		job.UpdatedAt = time.Now()
+
+		data, err := json.Marshal(job)
+		if err != nil {
+			return fmt.Errorf("cannot marshal job %s: %v", videoId, err)
+		}
+		return bucket.Put([]byte(videoId), data)
+	})
+}
+
+// SaveVideoInfo records the request a job was started from so it can be replayed after a restart
+func SaveVideoInfo(videoInfo messagemodel.VideoInfo) error {
+	err := update(videoInfo.VideoId, func(job *Job) {
+		job.VideoInfo = videoInfo
+		job.ProcessedSegments = nil
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to save job video info", zap.Error(err), zap.String("videoId", videoInfo.VideoId))
+	}
+	return err
+}
+
+// SaveProcessedSegments records the output of a run that is ready to be uploaded
+func SaveProcessedSegments(segmentsInfo messagemodel.ProcessedSegmentsInfo) error {
+	err := update(segmentsInfo.VideoId, func(job *Job) {
+		job.ProcessedSegments = &segmentsInfo
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to save job processed segments", zap.Error(err), zap.String("videoId", segmentsInfo.VideoId))
+	}
+	return err
+}
+
+func UpdateStage(videoId, stage string, finished bool) error {
+	err := update(videoId, func(job *Job) {
+		job.Stage = stage
+		job.Finished = finished
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to update job stage", zap.Error(err), zap.String("videoId", videoId), zap.String("stage", stage))
+	}
+	return err
+}
+
+func RecordAttempt(videoId string) error {
+	err := update(videoId, func(job *Job) {
+		job.Attempts++
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to record job attempt", zap.Error(err), zap.String("videoId", videoId))
+	}
+	return err
+}
+
+func RecordError(videoId string, jobErr error) error {
+	err := update(videoId, func(job *Job) {
+		job.Errors = append(job.Errors, jobErr.Error())
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to record job error", zap.Error(err), zap.String("videoId", videoId))
+	}
+	return err
+}
+
+func SetSourceETag(videoId, etag string) error {
+	err := update(videoId, func(job *Job) {
+		job.SourceETag = etag
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to set job source etag", zap.Error(err), zap.String("videoId", videoId))
+	}
+	return err
+}
+
+// MarkCompleted remembers that the current source content has a finished result and where it was uploaded
+func MarkCompleted(videoId string, uploadedInfo messagemodel.SegmentsUploadedInfo) error {
+	err := update(videoId, func(job *Job) {
+		job.CompletedSourceETag = job.SourceETag
+		job.UploadedSegments = &uploadedInfo
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to mark job completed", zap.Error(err), zap.String("videoId", videoId))
+	}
+	return err
+}
+
+// HasCompleted reports whether the same source object and content were already processed for the video
+func HasCompleted(videoId, rawVidS3Key, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	job, err := GetJob(videoId)
+	if err != nil {
+		return false
+	}
+
+	return job.VideoInfo.RawVidS3Key == rawVidS3Key && job.CompletedSourceETag == etag
+}
+
+func GetJob(videoId string) (Job, error) {
+	var job Job
+	if db == nil {
+		return job, ErrJobNotFound
+	}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(jobsBucket).Get([]byte(videoId))
+		if data == nil {
+			return ErrJobNotFound
+		}
+		return json.Unmarshal(data, &job)
+	})
+	return job, err
+}
+
+// UnfinishedJobs returns every job that was queued or running when the process stopped
+func UnfinishedJobs() ([]Job, error) {
+	var jobs []Job
+	if db == nil {
+		return jobs, nil
+	}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(key, data []byte) error {
+			var job Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				logger.AppLogger.Error("Skipping unreadable job", zap.Error(err), zap.String("videoId", string(key)))
+				return nil
+			}
+			if !job.Finished {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logger.AppLogger.Error("Failed to list unfinished jobs", zap.Error(err))
+		return nil, fmt.Errorf("failed to list unfinished jobs: %v", err)
+	}
+
+	return jobs, nil
+}
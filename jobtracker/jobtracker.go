@@ -0,0 +1,385 @@
+package jobtracker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"video_processor/appconst"
+	"video_processor/jobstore"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+type Stage string
+
+const (
+	StageQueued      Stage = "queued"
+	StageDownloading Stage = "downloading"
+	StageSegmenting  Stage = "segmenting"
+	StageUploading   Stage = "uploading"
+	StageDone        Stage = "done"
+	StageFailed      Stage = "failed"
+	StageCancelled   Stage = "cancelled"
+)
+
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrJobNotRunning = errors.New("job is not running")
+	ErrJobRunning    = errors.New("job is already running")
+	ErrJobCancelled  = errors.New("job was cancelled")
+)
+
+// IsTerminal reports whether the job has reached a stage it will not leave without being queued again
+func (s Stage) IsTerminal() bool {
+	return s == StageDone || s == StageFailed || s == StageCancelled
+}
+
+type RenditionProgress struct {
+	Name       string
+	Percentage float64
+}
+
+// JobStatus is a snapshot of a video processing job
+type JobStatus struct {
+	VideoId    string
+	Stage      Stage
+	Renditions []RenditionProgress
+	Error      string
+	UpdatedAt  time.Time
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running bool
+}
+
+type EventType string
+
+const (
+	EventStage     EventType = "stage"
+	EventProgress  EventType = "progress"
+	EventSucceeded EventType = "succeeded"
+	EventFailed    EventType = "failed"
+	EventCancelled EventType = "cancelled"
+)
+
+// Event is pushed to watchers whenever a job changes
+type Event struct {
+	VideoId    string
+	Type       EventType
+	Stage      Stage
+	Rendition  string
+	Percentage float64
+	Error      string
+	Timestamp  time.Time
+}
+
+// IsFinal reports whether no more events will follow for this run of the job
+func (e Event) IsFinal() bool {
+	return e.Type == EventSucceeded || e.Type == EventFailed || e.Type == EventCancelled
+}
+
+const subscriberBufferSize = 64
+
+var (
+	mu          sync.RWMutex
+	jobs        = make(map[string]*JobStatus)
+	subscribers = make(map[string]map[chan Event]struct{})
+	lastSweep   = time.Now()
+)
+
+func getOrCreate(videoId string) *JobStatus {
+	if time.Since(lastSweep) >= appconst.JobTrackerSweepInterval {
+		evictFinished()
+		lastSweep = time.Now()
+	}
+
+	job, ok := jobs[videoId]
+	if !ok {
+		job = &JobStatus{VideoId: videoId}
+		job.ctx, job.cancel = context.WithCancel(context.Background())
+		jobs[videoId] = job
+	}
+	return job
+}
+
+// evictFinished drops the jobs that ended more than JobTrackerRetention ago, nobody watches
+// them anymore. It must be called with mu held.
+func evictFinished() {
+	for videoId, job := range jobs {
+		finished := job.Stage == "" || job.Stage.IsTerminal()
+		if !finished || job.running || len(subscribers[videoId]) > 0 {
+			continue
+		}
+		if time.Since(job.UpdatedAt) >= appconst.JobTrackerRetention {
+			job.release()
+			delete(jobs, videoId)
+		}
+	}
+}
+
+// release frees the job context once the job can no longer be cancelled
+func (job *JobStatus) release() {
+	job.running = false
+	if job.cancel != nil {
+		job.cancel()
+	}
+}
+
+// Start claims the job for a worker and returns the context of the run.
+// Only one run of a video can be in progress at a time.
+func Start(videoId string) (context.Context, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	job := getOrCreate(videoId)
+	if job.running {
+		return nil, ErrJobRunning
+	}
+	if job.Stage == StageCancelled {
+		return nil, ErrJobCancelled
+	}
+	if job.ctx.Err() != nil {
+		// The previous run is over, e.g. the message was published by another instance
+		job.ctx, job.cancel = context.WithCancel(context.Background())
+	}
+	job.running = true
+
+	return job.ctx, nil
+}
+
+// Handoff ends the claim of the current worker without ending the run, so the worker of the
+// next stage can Start it with the same context
+func Handoff(videoId string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if job, ok := jobs[videoId]; ok {
+		job.running = false
+	}
+}
+
+func IsRunning(videoId string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	job, ok := jobs[videoId]
+	return ok && job.running
+}
+
+// Context returns the context of the current run of the job. It is cancelled by Cancel.
+func Context(videoId string) context.Context {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return getOrCreate(videoId).ctx
+}
+
+// Enqueue queues a new run of the job unless one is already queued or in progress. It checks
+// and queues under one lock, so of two requests arriving together only one gets the run.
+func Enqueue(videoId string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	job := getOrCreate(videoId)
+	if job.running || (job.Stage != "" && !job.Stage.IsTerminal()) {
+		return ErrJobRunning
+	}
+	setStage(job, StageQueued)
+	return nil
+}
+
+func SetStage(videoId string, stage Stage) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	job := getOrCreate(videoId)
+	if stage == StageQueued && job.running {
+		// A duplicate request must not reset the run in progress
+		logger.AppLogger.Info("Job already running, not queuing it again", zap.String("videoId", videoId))
+		return
+	}
+	setStage(job, stage)
+}
+
+// setStage must be called with mu held
+func setStage(job *JobStatus, stage Stage) {
+	videoId := job.VideoId
+	if stage == StageQueued {
+		// A new run of the same video starts from scratch
+		job.Renditions = nil
+		job.Error = ""
+		if job.ctx.Err() != nil {
+			job.ctx, job.cancel = context.WithCancel(context.Background())
+		}
+	} else if job.Stage == StageCancelled {
+		// Workers still winding down must not resurrect a cancelled job
+		return
+	}
+	job.Stage = stage
+	job.UpdatedAt = time.Now()
+
+	logger.AppLogger.Info("Job stage changed", zap.String("videoId", videoId), zap.String("stage", string(stage)))
+	jobstore.UpdateStage(videoId, string(stage), stage.IsTerminal())
+
+	eventType := EventStage
+	if stage == StageDone {
+		eventType = EventSucceeded
+		job.release()
+	}
+	publish(Event{VideoId: videoId, Type: eventType, Stage: stage, Timestamp: job.UpdatedAt})
+}
+
+func SetFailed(videoId string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	job := getOrCreate(videoId)
+	if job.Stage == StageCancelled {
+		return
+	}
+	job.Stage = StageFailed
+	if err != nil {
+		job.Error = err.Error()
+	}
+	job.UpdatedAt = time.Now()
+	job.release()
+
+	logger.AppLogger.Error("Job failed", zap.String("videoId", videoId), zap.Error(err))
+	jobstore.UpdateStage(videoId, string(StageFailed), true)
+	if err != nil {
+		jobstore.RecordError(videoId, err)
+	}
+
+	publish(Event{VideoId: videoId, Type: EventFailed, Stage: StageFailed, Error: job.Error, Timestamp: job.UpdatedAt})
+}
+
+func SetRenditionProgress(videoId, rendition string, percentage float64) {
+	if percentage > 100 {
+		percentage = 100
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	job := getOrCreate(videoId)
+	if job.Stage == StageCancelled {
+		return
+	}
+	job.UpdatedAt = time.Now()
+
+	found := false
+	for i := range job.Renditions {
+		if job.Renditions[i].Name == rendition {
+			job.Renditions[i].Percentage = percentage
+			found = true
+			break
+		}
+	}
+	if !found {
+		job.Renditions = append(job.Renditions, RenditionProgress{Name: rendition, Percentage: percentage})
+	}
+
+	publish(Event{
+		VideoId:    videoId,
+		Type:       EventProgress,
+		Stage:      job.Stage,
+		Rendition:  rendition,
+		Percentage: percentage,
+		Timestamp:  job.UpdatedAt,
+	})
+}
+
+// Cancel stops the current run of the job. Workers observe it through the job context.
+func Cancel(videoId string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	job, ok := jobs[videoId]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.Stage.IsTerminal() {
+		return ErrJobNotRunning
+	}
+
+	job.release()
+	job.Stage = StageCancelled
+	job.UpdatedAt = time.Now()
+
+	logger.AppLogger.Info("Job cancelled", zap.String("videoId", videoId))
+	jobstore.UpdateStage(videoId, string(StageCancelled), true)
+
+	publish(Event{VideoId: videoId, Type: EventCancelled, Stage: StageCancelled, Timestamp: job.UpdatedAt})
+	return nil
+}
+
+// GetStatus returns a copy of the job status so callers can read it without holding the lock
+func GetStatus(videoId string) (JobStatus, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	job, ok := jobs[videoId]
+	if !ok {
+		return JobStatus{}, false
+	}
+
+	status := *job
+	status.ctx, status.cancel = nil, nil
+	status.Renditions = append([]RenditionProgress(nil), job.Renditions...)
+	return status, true
+}
+
+// Subscribe registers a watcher for the given video. The returned function must be called
+// to release the subscription once the caller stops reading.
+func Subscribe(videoId string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	mu.Lock()
+	if subscribers[videoId] == nil {
+		subscribers[videoId] = make(map[chan Event]struct{})
+	}
+	subscribers[videoId][ch] = struct{}{}
+	mu.Unlock()
+
+	unsubscribe := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(subscribers[videoId], ch)
+		if len(subscribers[videoId]) == 0 {
+			delete(subscribers, videoId)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// publish must be called with mu held
+func publish(event Event) {
+	for ch := range subscribers[event.VideoId] {
+		select {
+		case ch <- event:
+			continue
+		default:
+		}
+
+		if !event.IsFinal() {
+			// Progress updates are frequent, a slow watcher only misses intermediate ones
+			if event.Type != EventProgress {
+				logger.AppLogger.Warn("Dropped job event for slow watcher",
+					zap.String("videoId", event.VideoId),
+					zap.String("type", string(event.Type)))
+			}
+			continue
+		}
+
+		// A watcher waits for the final event to end its stream, make room by dropping the
+		// oldest event. Only publish sends and mu is held, so the send cannot block.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- event
+	}
+}
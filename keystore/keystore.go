@@ -0,0 +1,98 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"video_processor/appconst"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+// KeySize is the size of an AES-128 content key
+const KeySize = 16
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyStore keeps the content keys of encrypted videos. The key server that players fetch
+// the EXT-X-KEY URIs from reads the keys back through the same store.
+type KeyStore interface {
+	PutKey(videoId, keyId string, key []byte) error
+	GetKey(videoId, keyId string) ([]byte, error)
+}
+
+// Store is the key store used by the segmenter, replaced by main when configured
+var Store KeyStore = NewLocalKeyStore(appconst.DefaultKeyStoreDir)
+
+// GenerateKey returns a random AES-128 key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return key, nil
+}
+
+// LocalKeyStore keeps every key in its own file, <dir>/<video id>/<key id>.key
+type LocalKeyStore struct {
+	dir string
+}
+
+func NewLocalKeyStore(dir string) *LocalKeyStore {
+	return &LocalKeyStore{dir: dir}
+}
+
+func (s *LocalKeyStore) PutKey(videoId, keyId string, key []byte) error {
+	path, err := s.keyPath(videoId, keyId)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		logger.AppLogger.Error("Failed to create key directory", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to create key directory: %v", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated key behind
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, key, 0600); err != nil {
+		logger.AppLogger.Error("Failed to write key", zap.Error(err), zap.String("path", tmpPath))
+		return fmt.Errorf("failed to write key: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		logger.AppLogger.Error("Failed to store key", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to store key: %v", err)
+	}
+
+	return nil
+}
+
+func (s *LocalKeyStore) GetKey(videoId, keyId string) ([]byte, error) {
+	path, err := s.keyPath(videoId, keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %v", err)
+	}
+	return key, nil
+}
+
+// keyPath keeps ids from escaping the key directory
+func (s *LocalKeyStore) keyPath(videoId, keyId string) (string, error) {
+	for _, id := range []string{videoId, keyId} {
+		if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+			return "", fmt.Errorf("invalid key path component %q", id)
+		}
+	}
+	return filepath.Join(s.dir, videoId, keyId+".key"), nil
+}
@@ -3,9 +3,15 @@ package main
 import (
 	"log"
 	"net"
+	"os"
+	"video_processor/appconst"
 	"video_processor/grpcserver"
+	"video_processor/hlssegmenter"
+	"video_processor/jobstore"
+	"video_processor/keystore"
 	pb "video_processor/proto/video_service/video_service"
 	redishander "video_processor/redishandler"
+	"video_processor/storagehandler"
 	"video_processor/watermill"
 
 	"github.com/joho/godotenv"
@@ -21,8 +27,42 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
-	// Start the subscriber in a goroutine
-	go watermill.SubscribeToTopics()
+	jobStorePath := os.Getenv("JOB_STORE_PATH")
+	if jobStorePath == "" {
+		jobStorePath = appconst.DefaultJobStorePath
+	}
+	if err := jobstore.Open(jobStorePath); err != nil {
+		log.Fatalf("Failed to open job store: %v", err)
+	}
+	defer jobstore.Close()
+
+	if ladderConfigPath := os.Getenv("RENDITION_LADDER_CONFIG"); ladderConfigPath != "" {
+		if err := hlssegmenter.LoadRenditionLadder(ladderConfigPath); err != nil {
+			log.Fatalf("Failed to load rendition ladder: %v", err)
+		}
+	}
+
+	redishander.Connect()
+
+	store, err := storagehandler.NewStorageFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to configure storage: %v", err)
+	}
+	storagehandler.Store = store
+
+	if keyStoreDir := os.Getenv("KEY_STORE_DIR"); keyStoreDir != "" {
+		keystore.Store = keystore.NewLocalKeyStore(keyStoreDir)
+	}
+
+	if os.Getenv("MESSAGE_TRANSPORT") == appconst.MessageTransportRedisStream {
+		watermill.UseRedisStreams(redishander.RedisClient, redisStreamConfig())
+	}
+
+	// Subscribe before anything is published, the dispatching runs in the background
+	watermill.SubscribeToTopics()
+
+	// Pick up jobs that were queued or running when the process last stopped
+	watermill.ResumeUnfinishedJobs()
 
 	go redishander.StartRedisSubscribers(redishander.RedisClient)
 
@@ -43,6 +83,34 @@ func main() {
 	startGRPCServer()
 }
 
+func redisStreamConfig() watermill.RedisStreamConfig {
+	consumerGroup := os.Getenv("REDIS_STREAM_CONSUMER_GROUP")
+	if consumerGroup == "" {
+		consumerGroup = appconst.RedisStreamConsumerGroup
+	}
+
+	consumer := os.Getenv("REDIS_STREAM_CONSUMER")
+	if consumer == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Fatalf("Failed to get hostname for redis stream consumer: %v", err)
+		}
+		consumer = hostname
+	}
+
+	return watermill.RedisStreamConfig{
+		ConsumerGroup:   consumerGroup,
+		Consumer:        consumer,
+		MaxLen:          appconst.RedisStreamMaxLen,
+		BlockTime:       appconst.RedisStreamBlockTime,
+		ClaimInterval:   appconst.RedisStreamClaimInterval,
+		MinIdleTime:     appconst.RedisStreamMinIdleTime,
+		RefreshInterval: appconst.RedisStreamRefreshInterval,
+		MaxInFlight:     appconst.RedisStreamMaxInFlight,
+		NackResendSleep: appconst.RedisStreamNackResendSleep,
+	}
+}
+
 func startGRPCServer() {
 	lis, err := net.Listen("tcp", ":50052")
 	if err != nil {
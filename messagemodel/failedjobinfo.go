@@ -0,0 +1,16 @@
+package messagemodel
+
+import "encoding/json"
+
+// FailedJobInfo is published to the dead-letter topic once a message ran out of attempts.
+// OriginalPayload can be published to OriginalTopic again to replay the job.
+type FailedJobInfo struct {
+	VideoId         string          `json:"video_id"`
+	Stage           string          `json:"stage"`
+	Attempts        int             `json:"attempts"`
+	ErrorChain      []string        `json:"error_chain"`
+	OriginalTopic   string          `json:"original_topic"`
+	OriginalMsgId   string          `json:"original_msg_id"`
+	OriginalPayload json.RawMessage `json:"original_payload"`
+	FailedAt        int64           `json:"failed_at"`
+}
@@ -0,0 +1,9 @@
+package messagemodel
+
+// PosterImage is one size and format of the cover image of a video
+type PosterImage struct {
+	Key    string `json:"key"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
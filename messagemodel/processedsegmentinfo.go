@@ -1,8 +1,17 @@
 package messagemodel
 
 type ProcessedSegmentsInfo struct {
-	UploadedBy     string `json:"uploaded_by"`
-	CourseId       string `json:"course_id"`
-	VideoId        string `json:"video_id"`
+	UploadedBy string `json:"uploaded_by"`
+	CourseId   string `json:"course_id"`
+	VideoId    string `json:"video_id"`
+	// RunId tells the runs of a video apart, every run is uploaded under a prefix of its own
+	RunId          string `json:"run_id,omitempty"`
 	LocalOutputDir string `json:"local_output_dir"`
+	// MasterPlaylist, DashManifest and Thumbnails are relative to LocalOutputDir
+	MasterPlaylist string `json:"master_playlist"`
+	DashManifest   string `json:"dash_manifest,omitempty"`
+	Thumbnails     string `json:"thumbnails,omitempty"`
+	// Posters are the cover images, keyed relative to LocalOutputDir. Empty unless the
+	// request asked for a poster.
+	Posters []PosterImage `json:"posters,omitempty"`
 }
@@ -0,0 +1,15 @@
+package messagemodel
+
+// Rendition is one rung of the encoding ladder
+type Rendition struct {
+	Name             string `json:"name"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	VideoBitrateKbps int    `json:"video_bitrate_kbps"`
+	MaxrateKbps      int    `json:"maxrate_kbps"`
+	BufsizeKbps      int    `json:"bufsize_kbps"`
+	AudioBitrateKbps int    `json:"audio_bitrate_kbps"`
+	SegmentDuration  int    `json:"segment_duration"`
+	// Codec is "h264", "hevc" or "av1", h264 when empty
+	Codec string `json:"codec,omitempty"`
+}
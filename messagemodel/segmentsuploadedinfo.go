@@ -0,0 +1,16 @@
+package messagemodel
+
+// SegmentsUploadedInfo is published once every file of a processed video is uploaded.
+// All keys are full object keys under KeyPrefix.
+type SegmentsUploadedInfo struct {
+	UploadedBy        string `json:"uploaded_by"`
+	CourseId          string `json:"course_id"`
+	VideoId           string `json:"video_id"`
+	KeyPrefix         string `json:"key_prefix"`
+	MasterPlaylistKey string `json:"master_playlist_key"`
+	// DashManifestKey is empty unless a DASH manifest was generated
+	DashManifestKey string `json:"dash_manifest_key,omitempty"`
+	// ThumbnailsKey is the WebVTT storyboard, empty unless thumbnails were generated
+	ThumbnailsKey string        `json:"thumbnails_key,omitempty"`
+	Posters       []PosterImage `json:"posters,omitempty"`
+}
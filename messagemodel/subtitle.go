@@ -0,0 +1,9 @@
+package messagemodel
+
+// Subtitle is a caption file uploaded next to the video, SRT or WebVTT
+type Subtitle struct {
+	S3Key    string `json:"s3key"`
+	Language string `json:"language"`
+	// Name is shown in the player's subtitle menu, the language is shown when empty
+	Name string `json:"name,omitempty"`
+}
@@ -7,4 +7,27 @@ type VideoInfo struct {
 	UploadedBy  string `json:"uploaded_by"`
 	CourseId    string `json:"course_id"`
 	VideoId     string `json:"video_id"`
+	// Renditions overrides the configured ladder for this video when set
+	Renditions []Rendition `json:"renditions,omitempty"`
+	// ScaleMode is "fit" or "pad", the configured mode is used when empty
+	ScaleMode string `json:"scale_mode,omitempty"`
+	// Packaging is "ts" or "fmp4", the configured packaging is used when empty
+	Packaging string `json:"packaging,omitempty"`
+	// Dash adds an MPEG-DASH manifest next to the HLS master playlist
+	Dash bool `json:"dash,omitempty"`
+	// Encrypt encrypts the segments with AES-128, rotating the key every KeyRotationSegments
+	// segments when it is set
+	Encrypt             bool `json:"encrypt,omitempty"`
+	KeyRotationSegments int  `json:"key_rotation_segments,omitempty"`
+	// DemuxAudio gives every audio track of the source a rendition of its own,
+	// AudioOnlyVariant also offers the audio without video
+	DemuxAudio       bool `json:"demux_audio,omitempty"`
+	AudioOnlyVariant bool `json:"audio_only_variant,omitempty"`
+	// Subtitles become WebVTT renditions of the video
+	Subtitles []Subtitle `json:"subtitles,omitempty"`
+	// Thumbnails adds seek bar sprite sheets with a frame every ThumbnailInterval seconds
+	Thumbnails        bool `json:"thumbnails,omitempty"`
+	ThumbnailInterval int  `json:"thumbnail_interval,omitempty"`
+	// Poster adds a cover image picked from the video
+	Poster bool `json:"poster,omitempty"`
 }
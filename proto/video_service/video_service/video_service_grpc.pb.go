@@ -20,6 +20,9 @@ const _ = grpc.SupportPackageIsVersion8
 
 const (
 	VideoProcessingService_ProcessNewVideoRequest_FullMethodName = "/videoservice.VideoProcessingService/ProcessNewVideoRequest"
+	VideoProcessingService_GetProcessingStatus_FullMethodName    = "/videoservice.VideoProcessingService/GetProcessingStatus"
+	VideoProcessingService_WatchProcessing_FullMethodName        = "/videoservice.VideoProcessingService/WatchProcessing"
+	VideoProcessingService_CancelProcessing_FullMethodName       = "/videoservice.VideoProcessingService/CancelProcessing"
 )
 
 // VideoProcessingServiceClient is the client API for VideoProcessingService service.
@@ -27,6 +30,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type VideoProcessingServiceClient interface {
 	ProcessNewVideoRequest(ctx context.Context, in *VideoInfo, opts ...grpc.CallOption) (*ProcessNewVideoResponse, error)
+	GetProcessingStatus(ctx context.Context, in *ProcessingStatusRequest, opts ...grpc.CallOption) (*ProcessingStatusResponse, error)
+	WatchProcessing(ctx context.Context, in *ProcessingStatusRequest, opts ...grpc.CallOption) (VideoProcessingService_WatchProcessingClient, error)
+	CancelProcessing(ctx context.Context, in *CancelProcessingRequest, opts ...grpc.CallOption) (*CancelProcessingResponse, error)
 }
 
 type videoProcessingServiceClient struct {
@@ -47,11 +53,67 @@ func (c *videoProcessingServiceClient) ProcessNewVideoRequest(ctx context.Contex
 	return out, nil
 }
 
+func (c *videoProcessingServiceClient) GetProcessingStatus(ctx context.Context, in *ProcessingStatusRequest, opts ...grpc.CallOption) (*ProcessingStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProcessingStatusResponse)
+	err := c.cc.Invoke(ctx, VideoProcessingService_GetProcessingStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoProcessingServiceClient) WatchProcessing(ctx context.Context, in *ProcessingStatusRequest, opts ...grpc.CallOption) (VideoProcessingService_WatchProcessingClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VideoProcessingService_ServiceDesc.Streams[0], VideoProcessingService_WatchProcessing_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &videoProcessingServiceWatchProcessingClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VideoProcessingService_WatchProcessingClient interface {
+	Recv() (*ProcessingEvent, error)
+	grpc.ClientStream
+}
+
+type videoProcessingServiceWatchProcessingClient struct {
+	grpc.ClientStream
+}
+
+func (x *videoProcessingServiceWatchProcessingClient) Recv() (*ProcessingEvent, error) {
+	m := new(ProcessingEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *videoProcessingServiceClient) CancelProcessing(ctx context.Context, in *CancelProcessingRequest, opts ...grpc.CallOption) (*CancelProcessingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelProcessingResponse)
+	err := c.cc.Invoke(ctx, VideoProcessingService_CancelProcessing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // VideoProcessingServiceServer is the server API for VideoProcessingService service.
 // All implementations must embed UnimplementedVideoProcessingServiceServer
 // for forward compatibility
 type VideoProcessingServiceServer interface {
 	ProcessNewVideoRequest(context.Context, *VideoInfo) (*ProcessNewVideoResponse, error)
+	GetProcessingStatus(context.Context, *ProcessingStatusRequest) (*ProcessingStatusResponse, error)
+	WatchProcessing(*ProcessingStatusRequest, VideoProcessingService_WatchProcessingServer) error
+	CancelProcessing(context.Context, *CancelProcessingRequest) (*CancelProcessingResponse, error)
 	mustEmbedUnimplementedVideoProcessingServiceServer()
 }
 
@@ -62,6 +124,15 @@ type UnimplementedVideoProcessingServiceServer struct {
 func (UnimplementedVideoProcessingServiceServer) ProcessNewVideoRequest(context.Context, *VideoInfo) (*ProcessNewVideoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ProcessNewVideoRequest not implemented")
 }
+func (UnimplementedVideoProcessingServiceServer) GetProcessingStatus(context.Context, *ProcessingStatusRequest) (*ProcessingStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProcessingStatus not implemented")
+}
+func (UnimplementedVideoProcessingServiceServer) WatchProcessing(*ProcessingStatusRequest, VideoProcessingService_WatchProcessingServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProcessing not implemented")
+}
+func (UnimplementedVideoProcessingServiceServer) CancelProcessing(context.Context, *CancelProcessingRequest) (*CancelProcessingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelProcessing not implemented")
+}
 func (UnimplementedVideoProcessingServiceServer) mustEmbedUnimplementedVideoProcessingServiceServer() {
 }
 
@@ -94,6 +165,63 @@ func _VideoProcessingService_ProcessNewVideoRequest_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VideoProcessingService_GetProcessingStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessingStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoProcessingServiceServer).GetProcessingStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoProcessingService_GetProcessingStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoProcessingServiceServer).GetProcessingStatus(ctx, req.(*ProcessingStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoProcessingService_WatchProcessing_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProcessingStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VideoProcessingServiceServer).WatchProcessing(m, &videoProcessingServiceWatchProcessingServer{ServerStream: stream})
+}
+
+type VideoProcessingService_WatchProcessingServer interface {
+	Send(*ProcessingEvent) error
+	grpc.ServerStream
+}
+
+type videoProcessingServiceWatchProcessingServer struct {
+	grpc.ServerStream
+}
+
+func (x *videoProcessingServiceWatchProcessingServer) Send(m *ProcessingEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _VideoProcessingService_CancelProcessing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelProcessingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoProcessingServiceServer).CancelProcessing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoProcessingService_CancelProcessing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoProcessingServiceServer).CancelProcessing(ctx, req.(*CancelProcessingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // VideoProcessingService_ServiceDesc is the grpc.ServiceDesc for VideoProcessingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,7 +233,21 @@ var VideoProcessingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ProcessNewVideoRequest",
 			Handler:    _VideoProcessingService_ProcessNewVideoRequest_Handler,
 		},
+		{
+			MethodName: "GetProcessingStatus",
+			Handler:    _VideoProcessingService_GetProcessingStatus_Handler,
+		},
+		{
+			MethodName: "CancelProcessing",
+			Handler:    _VideoProcessingService_CancelProcessing_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchProcessing",
+			Handler:       _VideoProcessingService_WatchProcessing_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "video_service/video_service.proto",
 }
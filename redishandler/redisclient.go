@@ -10,7 +10,8 @@ import (
 
 var RedisClient *redis.Client
 
-func init() {
+// Connect creates RedisClient, main calls it once the .env file is loaded
+func Connect() {
 	// Get Redis configuration from environment variables
 	redisAddr := os.Getenv("REDIS_ADDR")
 	if redisAddr == "" {
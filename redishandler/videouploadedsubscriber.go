@@ -2,11 +2,16 @@ package redishander
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"video_processor/appconst"
+	"video_processor/jobstore"
+	"video_processor/jobtracker"
 	"video_processor/logger"
+	"video_processor/messagemodel"
 	"video_processor/watermill"
 
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -25,10 +30,7 @@ func StartRedisSubscribers(redisClient *redis.Client) {
 		log.Printf("Received message from Redis channel %s: %s", msg.Channel, msg.Payload)
 
 		// Parse the message payload
-		var videoInfo struct {
-			VideoID string `json:"video_id"`
-			// Add other fields as needed
-		}
+		var videoInfo messagemodel.VideoInfo
 		err := json.Unmarshal([]byte(msg.Payload), &videoInfo)
 		if err != nil {
 			log.Printf("Error parsing message payload: %v", err)
@@ -36,11 +38,55 @@ func StartRedisSubscribers(redisClient *redis.Client) {
 		}
 
 		// Create a Watermill message
-		watermillMsg := message.NewMessage(videoInfo.VideoID, []byte(msg.Payload))
+		watermillMsg := message.NewMessage(videoInfo.VideoId, []byte(msg.Payload))
+
+		claimed, err := claimBridge(ctx, redisClient, videoInfo.VideoId, msg.Payload)
+		if err != nil {
+			logger.AppLogger.Error("Failed to claim upload announcement", zap.Error(err), zap.String("videoId", videoInfo.VideoId))
+			continue
+		}
+		if !claimed {
+			logger.AppLogger.Info("Upload announcement bridged by another instance, skipping", zap.String("videoId", videoInfo.VideoId))
+			continue
+		}
+
+		if err := jobtracker.Enqueue(videoInfo.VideoId); err != nil {
+			logger.AppLogger.Info("Video already queued or processing, skipping", zap.String("videoId", videoInfo.VideoId))
+			continue
+		}
+
+		// Persisted before it is published, a crash before a handler picks it up must not lose it
+		jobstore.SaveVideoInfo(videoInfo)
 
 		// Process the message using the existing handler
 		if err := watermill.Publisher.Publish(appconst.TopicNewVideoUploaded, watermillMsg); err != nil {
 			logger.AppLogger.Error(fmt.Sprintf("Failed to publish %s event", appconst.TopicNewVideoUploaded), zap.Error(err))
+			// Release the claims so the video can be announced again
+			jobtracker.SetFailed(videoInfo.VideoId, err)
+			releaseBridge(ctx, redisClient, videoInfo.VideoId, msg.Payload)
 		}
 	}
 }
+
+// claimBridge makes sure an announcement reaches the topic once however many instances receive
+// it. The key holds the video id and a digest of the payload, so announcing a new upload of the
+// same video is bridged again.
+func claimBridge(ctx context.Context, redisClient *redis.Client, videoId string, payload string) (bool, error) {
+	key := bridgeKey(videoId, payload)
+	claimed, err := redisClient.SetNX(ctx, key, videoId, appconst.RedisBridgeKeyTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("cannot set bridge key %s: %v", key, err)
+	}
+	return claimed, nil
+}
+
+func releaseBridge(ctx context.Context, redisClient *redis.Client, videoId string, payload string) {
+	if err := redisClient.Del(ctx, bridgeKey(videoId, payload)).Err(); err != nil {
+		logger.AppLogger.Error("Failed to release upload announcement", zap.Error(err), zap.String("videoId", videoId))
+	}
+}
+
+func bridgeKey(videoId string, payload string) string {
+	digest := sha256.Sum256([]byte(payload))
+	return appconst.RedisBridgeKeyPrefix + videoId + ":" + hex.EncodeToString(digest[:])
+}
@@ -1,7 +1,11 @@
 package resolutionparser
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/exec"
 	"strconv"
@@ -71,6 +75,202 @@ func getVideoHeight(input string) (int, error) {
 	return height, nil
 }
 
+// VideoDimensions is the size a video is displayed at, after the sample aspect ratio and
+// the rotation metadata are applied
+type VideoDimensions struct {
+	Width    int
+	Height   int
+	Rotation int
+	// FrameRate is the average frame rate, 0 when ffprobe does not know it
+	FrameRate float64
+}
+
+type ffprobeStreams struct {
+	Streams []struct {
+		Width             int    `json:"width"`
+		Height            int    `json:"height"`
+		SampleAspectRatio string `json:"sample_aspect_ratio"`
+		AvgFrameRate      string `json:"avg_frame_rate"`
+		Tags              struct {
+			Rotate string `json:"rotate"`
+		} `json:"tags"`
+		SideDataList []struct {
+			Rotation int `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// GetVideoDimensions probes the display size of the first video stream. FFmpeg applies the
+// rotation while decoding, so a portrait phone recording reports a height larger than its width.
+func GetVideoDimensions(ctx context.Context, input string) (VideoDimensions, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height,sample_aspect_ratio,avg_frame_rate:stream_tags=rotate:stream_side_data=rotation",
+		"-of", "json",
+		input,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return VideoDimensions{}, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	var probe ffprobeStreams
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return VideoDimensions{}, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+	if len(probe.Streams) == 0 {
+		return VideoDimensions{}, fmt.Errorf("no video stream in %s", input)
+	}
+
+	stream := probe.Streams[0]
+	dimensions := VideoDimensions{Width: stream.Width, Height: stream.Height}
+	if num, den, ok := parseFraction(stream.AvgFrameRate); ok {
+		dimensions.FrameRate = float64(num) / float64(den)
+	}
+
+	// Anamorphic sources are stored narrower or wider than they are shown
+	if num, den, ok := parseRatio(stream.SampleAspectRatio); ok && num != den {
+		dimensions.Width = int(math.Round(float64(stream.Width) * float64(num) / float64(den)))
+	}
+
+	if rotate, err := strconv.Atoi(stream.Tags.Rotate); err == nil {
+		dimensions.Rotation = rotate
+	}
+	for _, sideData := range stream.SideDataList {
+		if sideData.Rotation != 0 {
+			dimensions.Rotation = sideData.Rotation
+		}
+	}
+	dimensions.Rotation = ((dimensions.Rotation % 360) + 360) % 360
+
+	if dimensions.Rotation == 90 || dimensions.Rotation == 270 {
+		dimensions.Width, dimensions.Height = dimensions.Height, dimensions.Width
+	}
+
+	return dimensions, nil
+}
+
+// GetStreamFrameRate probes the frame rate of the first video stream of a media read from r,
+// e.g. an encoded segment
+func GetStreamFrameRate(ctx context.Context, r io.Reader) (float64, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=avg_frame_rate,r_frame_rate",
+		"-of", "json",
+		"pipe:0",
+	)
+	cmd.Stdin = r
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			AvgFrameRate string `json:"avg_frame_rate"`
+			RFrameRate   string `json:"r_frame_rate"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return 0, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+	if len(probe.Streams) == 0 {
+		return 0, fmt.Errorf("no video stream")
+	}
+
+	// A short segment may not carry enough frames for an average
+	for _, rate := range []string{probe.Streams[0].AvgFrameRate, probe.Streams[0].RFrameRate} {
+		if num, den, ok := parseFraction(rate); ok {
+			return float64(num) / float64(den), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown frame rate")
+}
+
+// AudioStream is an audio track of the source
+type AudioStream struct {
+	// Index counts the audio streams only, it is the N of the 0:a:N stream specifier
+	Index    int
+	Channels int
+	Language string
+	Title    string
+	Default  bool
+}
+
+type ffprobeAudioStreams struct {
+	Streams []struct {
+		Channels    int `json:"channels"`
+		Disposition struct {
+			Default int `json:"default"`
+		} `json:"disposition"`
+		Tags struct {
+			Language string `json:"language"`
+			Title    string `json:"title"`
+		} `json:"tags"`
+	} `json:"streams"`
+}
+
+// GetAudioStreams probes the audio tracks of the source, e.g. one per language
+func GetAudioStreams(ctx context.Context, input string) ([]AudioStream, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "a",
+		"-show_entries", "stream=channels:stream_disposition=default:stream_tags=language,title",
+		"-of", "json",
+		input,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	var probe ffprobeAudioStreams
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+
+	streams := make([]AudioStream, 0, len(probe.Streams))
+	for i, stream := range probe.Streams {
+		streams = append(streams, AudioStream{
+			Index:    i,
+			Channels: stream.Channels,
+			Language: stream.Tags.Language,
+			Title:    stream.Tags.Title,
+			Default:  stream.Disposition.Default == 1,
+		})
+	}
+	return streams, nil
+}
+
+func parseRatio(ratio string) (int, int, bool) {
+	return parseParts(strings.Split(ratio, ":"))
+}
+
+// parseFraction parses an ffprobe rate such as 30000/1001
+func parseFraction(fraction string) (int, int, bool) {
+	return parseParts(strings.Split(fraction, "/"))
+}
+
+func parseParts(parts []string) (int, int, bool) {
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	num, err := strconv.Atoi(parts[0])
+	if err != nil || num <= 0 {
+		return 0, 0, false
+	}
+	den, err := strconv.Atoi(parts[1])
+	if err != nil || den <= 0 {
+		return 0, 0, false
+	}
+	return num, den, true
+}
+
 func segmentVideo(input string, output string, resolution int) error {
 	cmd := exec.Command("ffmpeg",
 		"-i", input,
@@ -0,0 +1,172 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"video_processor/appconst"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+type Stage string
+
+const (
+	StageS3Download Stage = "s3_download"
+	StageFFmpeg     Stage = "ffmpeg"
+	StageS3Upload   Stage = "s3_upload"
+)
+
+// Policy retries an operation with exponential backoff
+type Policy struct {
+	Stage          Stage
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// ExhaustedError is returned once every attempt of a policy failed. It keeps the error of each attempt.
+type ExhaustedError struct {
+	Stage    Stage
+	Attempts []error
+}
+
+func (e *ExhaustedError) Error() string {
+	messages := make([]string, 0, len(e.Attempts))
+	for i, err := range e.Attempts {
+		messages = append(messages, fmt.Sprintf("attempt %d: %v", i+1, err))
+	}
+	return fmt.Sprintf("%s failed after %d attempts: %s", e.Stage, len(e.Attempts), strings.Join(messages, "; "))
+}
+
+func (e *ExhaustedError) Unwrap() []error {
+	return e.Attempts
+}
+
+// permanentError marks a failure that another attempt cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent makes Do give up on the error right away instead of retrying it
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// ForStage builds the policy of a stage from the defaults, overridable through
+// RETRY_<STAGE>_MAX_ATTEMPTS, RETRY_<STAGE>_INITIAL_BACKOFF and RETRY_<STAGE>_MAX_BACKOFF
+func ForStage(stage Stage) Policy {
+	policy := Policy{
+		Stage:          stage,
+		MaxAttempts:    appconst.RetryDefaultMaxAttempts,
+		InitialBackoff: appconst.RetryDefaultInitialBackoff,
+		MaxBackoff:     appconst.RetryDefaultMaxBackoff,
+		Multiplier:     appconst.RetryDefaultMultiplier,
+	}
+
+	envPrefix := "RETRY_" + strings.ToUpper(string(stage)) + "_"
+
+	if value := os.Getenv(envPrefix + "MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 {
+			logger.AppLogger.Warn("Invalid retry max attempts, using default", zap.String("stage", string(stage)), zap.String("value", value))
+		} else {
+			policy.MaxAttempts = attempts
+		}
+	}
+
+	if value := os.Getenv(envPrefix + "INITIAL_BACKOFF"); value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil {
+			logger.AppLogger.Warn("Invalid retry initial backoff, using default", zap.String("stage", string(stage)), zap.String("value", value))
+		} else {
+			policy.InitialBackoff = backoff
+		}
+	}
+
+	if value := os.Getenv(envPrefix + "MAX_BACKOFF"); value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil {
+			logger.AppLogger.Warn("Invalid retry max backoff, using default", zap.String("stage", string(stage)), zap.String("value", value))
+		} else {
+			policy.MaxBackoff = backoff
+		}
+	}
+
+	return policy
+}
+
+// Do runs fn until it succeeds, the attempts run out or ctx is cancelled
+func (p Policy) Do(ctx context.Context, operation string, fn func() error) error {
+	var attemptErrors []error
+	backoff := p.InitialBackoff
+
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		attemptErrors = append(attemptErrors, err)
+		var permanent *permanentError
+		if attempt == p.MaxAttempts || errors.As(err, &permanent) {
+			break
+		}
+
+		logger.AppLogger.Warn("Operation failed, retrying",
+			zap.Error(err),
+			zap.String("stage", string(p.Stage)),
+			zap.String("operation", operation),
+			zap.Int("attempt", attempt),
+			zap.Int("maxAttempts", p.MaxAttempts),
+			zap.Duration("backoff", backoff))
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+
+	logger.AppLogger.Error("Operation failed, no attempts left",
+		zap.String("stage", string(p.Stage)),
+		zap.String("operation", operation),
+		zap.Int("attempts", len(attemptErrors)))
+
+	return &ExhaustedError{Stage: p.Stage, Attempts: attemptErrors}
+}
+
+// ErrorChain flattens wrapped and joined errors into readable messages, outermost first
+func ErrorChain(err error) []string {
+	var chain []string
+	for err != nil {
+		chain = append(chain, err.Error())
+
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, inner := range multi.Unwrap() {
+				chain = append(chain, ErrorChain(inner)...)
+			}
+			break
+		}
+		err = errors.Unwrap(err)
+	}
+	return chain
+}
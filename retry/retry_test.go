@@ -0,0 +1,194 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func testPolicy(maxAttempts int) Policy {
+	return Policy{
+		Stage:          StageS3Download,
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+	}
+}
+
+func TestPolicyDo(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		results      []error
+		wantCalls    int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{
+			name:        "first attempt succeeds",
+			maxAttempts: 3,
+			results:     []error{nil},
+			wantCalls:   1,
+		},
+		{
+			name:        "succeeds after transient failures",
+			maxAttempts: 3,
+			results:     []error{errTransient, errTransient, nil},
+			wantCalls:   3,
+		},
+		{
+			name:         "attempts run out",
+			maxAttempts:  3,
+			results:      []error{errTransient, errTransient, errTransient},
+			wantCalls:    3,
+			wantErr:      true,
+			wantAttempts: 3,
+		},
+		{
+			name:         "permanent error stops right away",
+			maxAttempts:  3,
+			results:      []error{Permanent(errTransient)},
+			wantCalls:    1,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "wrapped permanent error stops right away",
+			maxAttempts:  3,
+			results:      []error{errTransient, fmt.Errorf("stat: %w", Permanent(errTransient))},
+			wantCalls:    2,
+			wantErr:      true,
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := testPolicy(tt.maxAttempts).Do(context.Background(), "test", func() error {
+				result := tt.results[calls]
+				calls++
+				return result
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Do() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var exhausted *ExhaustedError
+			if !errors.As(err, &exhausted) {
+				t.Fatalf("Do() error = %v, want an ExhaustedError", err)
+			}
+			if len(exhausted.Attempts) != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", len(exhausted.Attempts), tt.wantAttempts)
+			}
+			if !errors.Is(err, errTransient) {
+				t.Errorf("Do() error does not wrap the attempt errors: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := testPolicy(3).Do(ctx, "test", func() error {
+		calls++
+		cancel()
+		return errTransient
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestForStage(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Policy
+	}{
+		{
+			name: "defaults",
+			want: Policy{Stage: StageFFmpeg, MaxAttempts: 3, InitialBackoff: 2 * time.Second, MaxBackoff: time.Minute, Multiplier: 2},
+		},
+		{
+			name: "overrides",
+			env: map[string]string{
+				"RETRY_FFMPEG_MAX_ATTEMPTS":    "5",
+				"RETRY_FFMPEG_INITIAL_BACKOFF": "100ms",
+				"RETRY_FFMPEG_MAX_BACKOFF":     "10s",
+			},
+			want: Policy{Stage: StageFFmpeg, MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second, Multiplier: 2},
+		},
+		{
+			name: "invalid values keep the defaults",
+			env: map[string]string{
+				"RETRY_FFMPEG_MAX_ATTEMPTS":    "0",
+				"RETRY_FFMPEG_INITIAL_BACKOFF": "soon",
+			},
+			want: Policy{Stage: StageFFmpeg, MaxAttempts: 3, InitialBackoff: 2 * time.Second, MaxBackoff: time.Minute, Multiplier: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if got := ForStage(StageFFmpeg); got != tt.want {
+				t.Errorf("ForStage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorChain(t *testing.T) {
+	inner := errors.New("connection reset")
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: nil,
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("download: %w", inner),
+			want: []string{"download: connection reset", "connection reset"},
+		},
+		{
+			name: "exhausted",
+			err:  &ExhaustedError{Stage: StageS3Upload, Attempts: []error{inner}},
+			want: []string{"s3_upload failed after 1 attempts: attempt 1: connection reset", "connection reset"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorChain(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ErrorChain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package storagehandler
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// checkFreeSpace fails when the file system of dir has less than size bytes available
+func checkFreeSpace(dir string, size int64) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return fmt.Errorf("failed to read free disk space: %v", err)
+	}
+
+	free := int64(stat.Bavail) * int64(stat.Bsize)
+	if size > free {
+		return fmt.Errorf("%w: need %d bytes in %s, %d available", ErrInsufficientDiskSpace, size, dir, free)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package storagehandler
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// checkFreeSpace fails when the volume of dir has less than size bytes available
+func checkFreeSpace(dir string, size int64) error {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read free disk space: %v", err)
+	}
+
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &free, nil, nil); err != nil {
+		return fmt.Errorf("failed to read free disk space: %v", err)
+	}
+
+	if uint64(size) > free {
+		return fmt.Errorf("%w: need %d bytes in %s, %d available", ErrInsufficientDiskSpace, size, dir, free)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package storagehandler
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"video_processor/appconst"
+	"video_processor/logger"
+	"video_processor/retry"
+
+	"go.uber.org/zap"
+)
+
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// The ETag of an object uploaded in one request is the MD5 of its content, multipart
+// uploads get "<md5 of the part md5s>-<part count>" which says nothing about the content
+var md5ETagPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+const (
+	partialSuffix = ".part"
+	// The ETag of the object a partial file holds the beginning of
+	partialETagSuffix = ".part.etag"
+)
+
+// DownloadFile saves an object into saveDir under the base name of its key. It downloads
+// in ranges into a partial file, so a dropped connection resumes where it stopped when the
+// download is retried, as long as the object did not change in between.
+func DownloadFile(ctx context.Context, key, saveDir string) (string, error) {
+	info, err := Store.Stat(ctx, key)
+	if err != nil {
+		logger.AppLogger.Error("Failed to stat object", zap.Error(err), zap.String("key", key))
+		return "", notFoundIsPermanent(fmt.Errorf("failed to stat object: %w", err))
+	}
+
+	if err := os.MkdirAll(saveDir, 0755); err != nil {
+		logger.AppLogger.Error("Failed to create save directory", zap.Error(err), zap.String("directory", saveDir))
+		return "", fmt.Errorf("failed to create save directory: %v", err)
+	}
+
+	localPath := filepath.Join(saveDir, filepath.Base(key))
+	partialPath := localPath + partialSuffix
+	offset := resumeOffset(localPath, info)
+
+	if err := checkFreeSpace(saveDir, info.Size-offset); err != nil {
+		logger.AppLogger.Error("Refusing download", zap.Error(err), zap.String("key", key), zap.Int64("size", info.Size))
+		// Waiting does not free the disk, there is no point in retrying
+		return "", retry.Permanent(err)
+	}
+
+	if err := os.WriteFile(localPath+partialETagSuffix, []byte(info.ETag), 0644); err != nil {
+		return "", fmt.Errorf("failed to write partial download state: %v", err)
+	}
+	file, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.AppLogger.Error("Failed to create local file", zap.Error(err), zap.String("filePath", partialPath))
+		return "", fmt.Errorf("failed to create file: %v", err)
+	}
+	defer file.Close()
+	// Drop bytes written after the last complete chunk
+	if err := file.Truncate(offset); err != nil {
+		return "", fmt.Errorf("failed to truncate partial download: %v", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek partial download: %v", err)
+	}
+
+	if offset > 0 {
+		logger.AppLogger.Info("Resuming download", zap.String("key", key), zap.Int64("offset", offset), zap.Int64("size", info.Size))
+	}
+
+	for offset < info.Size {
+		length := min(int64(appconst.DownloadChunkSize), info.Size-offset)
+		written, err := downloadRange(ctx, file, key, offset, length, info.ETag)
+		offset += written
+		if err != nil {
+			if ctx.Err() != nil {
+				removePartial(localPath)
+			} else if errors.Is(err, ErrObjectChanged) {
+				// The next attempt starts over with the new content
+				removePartial(localPath)
+			}
+			logger.AppLogger.Error("Failed to download object range",
+				zap.Error(err),
+				zap.String("key", key),
+				zap.Int64("offset", offset))
+			return "", fmt.Errorf("failed to download object: %w", err)
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		return "", fmt.Errorf("failed to flush download: %v", err)
+	}
+	if err := verifyDownload(ctx, partialPath, key, info); err != nil {
+		removePartial(localPath)
+		logger.AppLogger.Error("Downloaded object failed verification", zap.Error(err), zap.String("key", key))
+		return "", err
+	}
+
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return "", fmt.Errorf("failed to move download into place: %v", err)
+	}
+	os.Remove(localPath + partialETagSuffix)
+
+	logger.AppLogger.Info("File downloaded successfully", zap.String("key", key), zap.String("localPath", localPath))
+	return localPath, nil
+}
+
+// downloadRange appends one range of the object to the file and returns how much of it was
+// written, whole chunks only count once they are complete
+func downloadRange(ctx context.Context, file *os.File, key string, offset, length int64, etag string) (int64, error) {
+	body, err := Store.GetRange(ctx, key, offset, length, etag)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	written, err := io.Copy(file, body)
+	if err == nil && written != length {
+		err = fmt.Errorf("short read: got %d of %d bytes", written, length)
+	}
+	if err != nil {
+		// A torn chunk is cut off by the next attempt
+		return 0, err
+	}
+	return written, nil
+}
+
+// resumeOffset is how much of the object a partial file from an earlier attempt holds,
+// 0 unless it was downloading the same version of the object
+func resumeOffset(localPath string, info ObjectInfo) int64 {
+	etag, err := os.ReadFile(localPath + partialETagSuffix)
+	if err != nil || string(etag) != info.ETag {
+		removePartial(localPath)
+		return 0
+	}
+
+	partial, err := os.Stat(localPath + partialSuffix)
+	if err != nil || partial.Size() > info.Size {
+		removePartial(localPath)
+		return 0
+	}
+
+	// Only whole chunks are trusted, the last one may have been cut by a crash
+	return partial.Size() / appconst.DownloadChunkSize * appconst.DownloadChunkSize
+}
+
+// verifyDownload checks the object did not change during the download and that the bytes on
+// disk are the ones that were uploaded, when the backend has a checksum of the content
+func verifyDownload(ctx context.Context, path, key string, info ObjectInfo) error {
+	current, err := Store.Stat(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to stat object: %w", err)
+	}
+	if current.ETag != info.ETag {
+		return fmt.Errorf("%s: %w during download", key, ErrObjectChanged)
+	}
+
+	downloaded, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat download: %v", err)
+	}
+	if downloaded.Size() != info.Size {
+		return fmt.Errorf("downloaded %d bytes, expected %d", downloaded.Size(), info.Size)
+	}
+
+	if info.ChecksumCRC32C == "" && !etagIsContentMD5(info) {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open download: %v", err)
+	}
+	defer file.Close()
+
+	if info.ChecksumCRC32C != "" {
+		sum, err := crc32cChecksum(file)
+		if err != nil {
+			return err
+		}
+		if sum != info.ChecksumCRC32C {
+			return fmt.Errorf("downloaded content has CRC32C %s, expected %s", sum, info.ChecksumCRC32C)
+		}
+		return nil
+	}
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to hash download: %v", err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != info.ETag {
+		return fmt.Errorf("downloaded content has MD5 %s, expected ETag %s", sum, info.ETag)
+	}
+	return nil
+}
+
+// etagIsContentMD5 reports whether the ETag is the MD5 of the content. It is not for multipart
+// uploads, nor for objects encrypted with SSE-KMS or SSE-C whatever it looks like.
+func etagIsContentMD5(info ObjectInfo) bool {
+	switch info.Encryption {
+	case "aws:kms", "aws:kms:dsse", "SSE-C":
+		return false
+	}
+	return md5ETagPattern.MatchString(info.ETag)
+}
+
+func removePartial(localPath string) {
+	for _, path := range []string{localPath + partialSuffix, localPath + partialETagSuffix} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.AppLogger.Warn("Failed to remove partial download", zap.Error(err), zap.String("path", path))
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package storagehandler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// useLocalStore points Store at a fresh local storage for the duration of the test
+func useLocalStore(t *testing.T) *LocalStorage {
+	t.Helper()
+
+	previous := Store
+	store := NewLocalStorage(t.TempDir())
+	Store = store
+	t.Cleanup(func() { Store = previous })
+	return store
+}
+
+func TestLocalStoragePutGet(t *testing.T) {
+	store := useLocalStore(t)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "course/a/video.m3u8", strings.NewReader("#EXTM3U\n"), ObjectMetadata{}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	info, err := store.Stat(ctx, "course/a/video.m3u8")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 8 {
+		t.Errorf("Size = %d, want 8", info.Size)
+	}
+
+	if runtime.GOOS != "windows" {
+		file, err := os.Stat(filepath.Join(store.root, "course", "a", "video.m3u8"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := file.Mode().Perm(); mode != 0644 {
+			t.Errorf("mode = %o, want 644", mode)
+		}
+	}
+
+	objects, err := store.List(ctx, "course/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "course/a/video.m3u8" {
+		t.Errorf("List() = %+v, want the one object", objects)
+	}
+
+	if err := store.Delete(ctx, "course/a/video.m3u8"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Stat(ctx, "course/a/video.m3u8"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Stat() after Delete error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestLocalStorageGetRange(t *testing.T) {
+	store := useLocalStore(t)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "source.mp4", strings.NewReader("0123456789"), ObjectMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := store.Stat(ctx, "source.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		offset  int64
+		length  int64
+		etag    string
+		want    string
+		wantErr error
+	}{
+		{name: "start", offset: 0, length: 4, etag: info.ETag, want: "0123"},
+		{name: "middle", offset: 3, length: 4, etag: info.ETag, want: "3456"},
+		{name: "end", offset: 8, length: 2, etag: info.ETag, want: "89"},
+		{name: "other version", offset: 0, length: 4, etag: "stale", wantErr: ErrObjectChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := store.GetRange(ctx, "source.mp4", tt.offset, tt.length, tt.etag)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetRange() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRange() error = %v", err)
+			}
+			defer body.Close()
+
+			var got bytes.Buffer
+			if _, err := got.ReadFrom(body); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("GetRange() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalStorageObjectPath(t *testing.T) {
+	store := NewLocalStorage("/data")
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "course/a/video.m3u8", want: filepath.Join("/data", "course", "a", "video.m3u8")},
+		{key: "../escape.ts", want: filepath.Join("/data", "escape.ts")},
+		{key: "a/../../b.ts", want: filepath.Join("/data", "b.ts")},
+		{key: "", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: `a\b.ts`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := store.objectPath(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("objectPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("objectPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	store := useLocalStore(t)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("segment"), 1000)
+	if err := store.Put(ctx, "raw/source.mp4", bytes.NewReader(content), ObjectMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+
+	saveDir := t.TempDir()
+	localPath, err := DownloadFile(ctx, "raw/source.mp4", saveDir)
+	if err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	if localPath != filepath.Join(saveDir, "source.mp4") {
+		t.Errorf("DownloadFile() path = %s", localPath)
+	}
+
+	downloaded, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("downloaded %d bytes that differ from the object", len(downloaded))
+	}
+	for _, suffix := range []string{partialSuffix, partialETagSuffix} {
+		if _, err := os.Stat(localPath + suffix); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", suffix, err)
+		}
+	}
+}
+
+func TestDownloadFileMissingObject(t *testing.T) {
+	useLocalStore(t)
+
+	_, err := DownloadFile(context.Background(), "raw/missing.mp4", t.TempDir())
+	if !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("DownloadFile() error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestEtagIsContentMD5(t *testing.T) {
+	md5ETag := "9e107d9d372bb6826bd81d3542a419d6"
+
+	tests := []struct {
+		name string
+		info ObjectInfo
+		want bool
+	}{
+		{name: "single request upload", info: ObjectInfo{ETag: md5ETag}, want: true},
+		{name: "SSE-S3", info: ObjectInfo{ETag: md5ETag, Encryption: "AES256"}, want: true},
+		{name: "multipart upload", info: ObjectInfo{ETag: md5ETag + "-3"}, want: false},
+		{name: "SSE-KMS", info: ObjectInfo{ETag: md5ETag, Encryption: "aws:kms"}, want: false},
+		{name: "DSSE-KMS", info: ObjectInfo{ETag: md5ETag, Encryption: "aws:kms:dsse"}, want: false},
+		{name: "SSE-C", info: ObjectInfo{ETag: md5ETag, Encryption: "SSE-C"}, want: false},
+		{name: "local storage", info: ObjectInfo{ETag: "17f0a1b2c3d4e5f6-3e8"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagIsContentMD5(tt.info); got != tt.want {
+				t.Errorf("etagIsContentMD5() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataForKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want ObjectMetadata
+	}{
+		{key: "v/master.m3u8", want: ObjectMetadata{ContentType: "application/vnd.apple.mpegurl", CacheControl: "public, max-age=60"}},
+		{key: "v/720p/segment_001.TS", want: ObjectMetadata{ContentType: "video/mp2t", CacheControl: "public, max-age=31536000, immutable"}},
+		{key: "v/720p/init.mp4", want: ObjectMetadata{ContentType: "video/mp4", CacheControl: "public, max-age=31536000, immutable"}},
+		{key: "v/poster.webp", want: ObjectMetadata{ContentType: "image/webp", CacheControl: "public, max-age=86400"}},
+		{key: "v/unknown.bin", want: ObjectMetadata{ContentType: "application/octet-stream"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := MetadataForKey(tt.key)
+			if got.ContentType != tt.want.ContentType || got.CacheControl != tt.want.CacheControl {
+				t.Errorf("MetadataForKey() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSegmentS3Key(t *testing.T) {
+	tests := []struct {
+		name string
+		info VideoInfo
+		want string
+	}{
+		{
+			name: "run",
+			info: VideoInfo{UploadedBy: "u", CourseId: "c", VideoId: "v", RunId: "r1"},
+			want: "course/u/c/v/video_segment/v/r1",
+		},
+		{
+			name: "output saved before runs had an id",
+			info: VideoInfo{UploadedBy: "u", CourseId: "c", VideoId: "v"},
+			want: "course/u/c/v/video_segment/v",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateSegmentS3Key(tt.info); got != tt.want {
+				t.Errorf("GenerateSegmentS3Key() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
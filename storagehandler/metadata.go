@@ -0,0 +1,58 @@
+package storagehandler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"video_processor/appconst"
+)
+
+// ObjectMetadata is sent along with an object, the CDN serves the content type and cache
+// control of the object as they are
+type ObjectMetadata struct {
+	ContentType  string
+	CacheControl string
+	// Tags are S3 object tags, e.g. for lifecycle rules or cost allocation per course
+	Tags map[string]string
+}
+
+type metadataRule struct {
+	contentType  string
+	cacheControl string
+}
+
+// metadataRules by file extension. Every run is uploaded under a prefix of its own, so segments
+// never change once written and are cached for good. Playlists and manifests are cached
+// briefly all the same.
+var metadataRules = map[string]metadataRule{
+	".m3u8": {"application/vnd.apple.mpegurl", appconst.PlaylistCacheControl},
+	".mpd":  {"application/dash+xml", appconst.PlaylistCacheControl},
+	".ts":   {"video/mp2t", appconst.SegmentCacheControl},
+	".m4s":  {"video/iso.segment", appconst.SegmentCacheControl},
+	".mp4":  {"video/mp4", appconst.SegmentCacheControl},
+	".vtt":  {"text/vtt", appconst.SegmentCacheControl},
+	".jpg":  {"image/jpeg", appconst.ImageCacheControl},
+	".webp": {"image/webp", appconst.ImageCacheControl},
+}
+
+// MetadataForKey picks the metadata of an object from the extension of its key
+func MetadataForKey(key string) ObjectMetadata {
+	rule, ok := metadataRules[strings.ToLower(path.Ext(key))]
+	if !ok {
+		return ObjectMetadata{ContentType: "application/octet-stream"}
+	}
+	return ObjectMetadata{ContentType: rule.contentType, CacheControl: rule.cacheControl}
+}
+
+// tagging encodes the tags the way the x-amz-tagging header takes them, empty without tags
+func (m ObjectMetadata) tagging() string {
+	if len(m.Tags) == 0 {
+		return ""
+	}
+
+	values := url.Values{}
+	for name, value := range m.Tags {
+		values.Set(name, value)
+	}
+	return values.Encode()
+}
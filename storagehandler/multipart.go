@@ -0,0 +1,47 @@
+package storagehandler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var ErrUploadNotFound = errors.New("multipart upload not found")
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// UploadedPart is a part of a multipart upload the backend has accepted
+type UploadedPart struct {
+	Number         int32  `json:"number"`
+	ETag           string `json:"etag"`
+	ChecksumCRC32C string `json:"checksum_crc32c"`
+}
+
+// MultipartStorage is implemented by backends that take large objects in parts. Parts are
+// uploaded in any order and survive a restart until the upload is completed or aborted.
+type MultipartStorage interface {
+	CreateMultipartUpload(ctx context.Context, key string, metadata ObjectMetadata) (string, error)
+	UploadPart(ctx context.Context, key, uploadId string, partNumber int32, body io.ReadSeeker) (UploadedPart, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadId string, parts []UploadedPart) error
+	AbortMultipartUpload(ctx context.Context, key, uploadId string) error
+}
+
+// crc32cChecksum returns the base64 encoded CRC32C of the body the way S3 expects it, and
+// rewinds the body for the upload
+func crc32cChecksum(body io.ReadSeeker) (string, error) {
+	hash := crc32.New(crc32cTable)
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", fmt.Errorf("failed to compute checksum: %v", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind body: %v", err)
+	}
+
+	sum := make([]byte, 4)
+	binary.BigEndian.PutUint32(sum, hash.Sum32())
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
@@ -2,109 +2,279 @@ package storagehandler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
-	"video_processor/appconst"
+	"net/http"
+	"strings"
 	"video_processor/logger"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/joho/godotenv"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"go.uber.org/zap"
 )
 
-var awsConfig aws.Config
+// S3Config configures an S3 compatible backend. Endpoint and UsePathStyle point it at
+// MinIO or another S3 compatible server instead of AWS.
+type S3Config struct {
+	Bucket          string
+	Region          string
+	Endpoint        string
+	UsePathStyle    bool
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Storage keeps every object in one bucket
+type S3Storage struct {
+	client *s3.Client
+	bucket string
+}
 
-func init() {
-	// Load the .env file
-	if err := godotenv.Load(); err != nil {
-		logger.AppLogger.Fatal("Error loading .env file", zap.Error(err))
+func NewS3Storage(ctx context.Context, s3Config S3Config) (*S3Storage, error) {
+	options := []func(*config.LoadOptions) error{config.WithRegion(s3Config.Region)}
+	if s3Config.AccessKeyID != "" {
+		options = append(options, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(s3Config.AccessKeyID, s3Config.SecretAccessKey, "")))
 	}
 
-	// Get credentials from environment variables
-	accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config: %v", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if s3Config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(s3Config.Endpoint)
+		}
+		o.UsePathStyle = s3Config.UsePathStyle
+	})
 
-	// Create a new credential provider
-	creds := credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")
+	logger.AppLogger.Info("S3 storage configured",
+		zap.String("bucket", s3Config.Bucket),
+		zap.String("region", s3Config.Region),
+		zap.String("endpoint", s3Config.Endpoint),
+		zap.Bool("pathStyle", s3Config.UsePathStyle))
+	return &S3Storage{client: client, bucket: s3Config.Bucket}, nil
+}
 
-	// Load the configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(appconst.AWSRegion),
-		config.WithCredentialsProvider(creds),
-	)
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		logger.AppLogger.Fatal("Unable to load SDK config", zap.Error(err))
+		return nil, s.wrapError(key, "failed to get object", err)
 	}
+	return result.Body, nil
+}
 
-	awsConfig = cfg
-	logger.AppLogger.Info("AWS configuration loaded successfully")
+func (s *S3Storage) GetRange(ctx context.Context, key string, offset, length int64, etag string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(key),
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+		IfMatch: aws.String(`"` + etag + `"`),
+	})
+	if err != nil {
+		var statusErr interface{ HTTPStatusCode() int }
+		if errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusPreconditionFailed {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectChanged)
+		}
+		return nil, s.wrapError(key, "failed to get object range", err)
+	}
+	return result.Body, nil
 }
 
-func UploadFileToS3(inputFilePath, bucketName string) error {
-	client := s3.NewFromConfig(awsConfig)
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, metadata ObjectMetadata) error {
+	input := &s3.PutObjectInput{
+		Bucket:            aws.String(s.bucket),
+		Key:               aws.String(key),
+		Body:              body,
+		ChecksumAlgorithm: types.ChecksumAlgorithmCrc32c,
+		ContentType:       optionalString(metadata.ContentType),
+		CacheControl:      optionalString(metadata.CacheControl),
+		Tagging:           optionalString(metadata.tagging()),
+	}
+	// A file is read twice rather than streamed with a trailing checksum, S3 compatible
+	// servers do not all accept trailers
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		checksum, err := crc32cChecksum(seeker)
+		if err != nil {
+			return err
+		}
+		input.ChecksumCRC32C = aws.String(checksum)
+	}
 
-	file, err := os.Open(inputFilePath)
+	_, err := s.client.PutObject(ctx, input)
 	if err != nil {
-		logger.AppLogger.Error("Error opening file", zap.Error(err), zap.String("filePath", inputFilePath))
-		return fmt.Errorf("error opening file: %w", err)
+		return s.wrapError(key, "failed to put object", err)
 	}
-	defer file.Close()
+	return nil
+}
 
-	fileName := filepath.Base(inputFilePath)
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				ETag:         strings.Trim(aws.ToString(object.ETag), `"`),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
 
-	_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(inputFilePath),
-		Body:   file,
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
 	})
 	if err != nil {
-		logger.AppLogger.Error("Error uploading file to S3", zap.Error(err), zap.String("bucket", bucketName), zap.String("key", inputFilePath))
-		return fmt.Errorf("error uploading file to S3: %w", err)
+		return s.wrapError(key, "failed to delete object", err)
 	}
-
-	logger.AppLogger.Info("File uploaded successfully", zap.String("filePath", inputFilePath), zap.String("bucket", bucketName), zap.String("key", fileName))
 	return nil
 }
 
-func GetS3File(bucket, key, saveDir string) (string, error) {
-	client := s3.NewFromConfig(awsConfig)
+func (s *S3Storage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return ObjectInfo{}, s.wrapError(key, "failed to head object", err)
+	}
 
-	result, err := client.GetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
+	info := ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(result.ContentLength),
+		ETag:         strings.Trim(aws.ToString(result.ETag), `"`),
+		LastModified: aws.ToTime(result.LastModified),
+		Encryption:   string(result.ServerSideEncryption),
+	}
+	if result.SSECustomerAlgorithm != nil {
+		info.Encryption = "SSE-C"
+	}
+	// A multipart object has a checksum of the part checksums, "<checksum>-<part count>"
+	if checksum := aws.ToString(result.ChecksumCRC32C); !strings.Contains(checksum, "-") {
+		info.ChecksumCRC32C = checksum
+	}
+	return info, nil
+}
+
+func (s *S3Storage) CreateMultipartUpload(ctx context.Context, key string, metadata ObjectMetadata) (string, error) {
+	result, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(s.bucket),
+		Key:               aws.String(key),
+		ChecksumAlgorithm: types.ChecksumAlgorithmCrc32c,
+		ContentType:       optionalString(metadata.ContentType),
+		CacheControl:      optionalString(metadata.CacheControl),
+		Tagging:           optionalString(metadata.tagging()),
 	})
 	if err != nil {
-		logger.AppLogger.Error("Failed to get object from S3", zap.Error(err), zap.String("bucket", bucket), zap.String("key", key))
-		return "", fmt.Errorf("failed to get object: %v", err)
+		return "", fmt.Errorf("failed to create multipart upload: %v", err)
+	}
+	return aws.ToString(result.UploadId), nil
+}
+
+func (s *S3Storage) UploadPart(ctx context.Context, key, uploadId string, partNumber int32, body io.ReadSeeker) (UploadedPart, error) {
+	checksum, err := crc32cChecksum(body)
+	if err != nil {
+		return UploadedPart{}, err
 	}
-	defer result.Body.Close()
 
-	if err := os.MkdirAll(saveDir, 0755); err != nil {
-		logger.AppLogger.Error("Failed to create save directory", zap.Error(err), zap.String("directory", saveDir))
-		return "", fmt.Errorf("failed to create save directory: %v", err)
+	result, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:         aws.String(s.bucket),
+		Key:            aws.String(key),
+		UploadId:       aws.String(uploadId),
+		PartNumber:     aws.Int32(partNumber),
+		Body:           body,
+		ChecksumCRC32C: aws.String(checksum),
+	})
+	if err != nil {
+		return UploadedPart{}, s.wrapUploadError(uploadId, "failed to upload part", err)
 	}
 
-	fileName := filepath.Base(key)
-	localPath := filepath.Join(saveDir, fileName)
+	return UploadedPart{
+		Number:         partNumber,
+		ETag:           aws.ToString(result.ETag),
+		ChecksumCRC32C: checksum,
+	}, nil
+}
+
+func (s *S3Storage) CompleteMultipartUpload(ctx context.Context, key, uploadId string, parts []UploadedPart) error {
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, types.CompletedPart{
+			PartNumber:     aws.Int32(part.Number),
+			ETag:           aws.String(part.ETag),
+			ChecksumCRC32C: aws.String(part.ChecksumCRC32C),
+		})
+	}
 
-	file, err := os.Create(localPath)
+	_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadId),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
 	if err != nil {
-		logger.AppLogger.Error("Failed to create local file", zap.Error(err), zap.String("filePath", localPath))
-		return "", fmt.Errorf("failed to create file: %v", err)
+		return s.wrapUploadError(uploadId, "failed to complete multipart upload", err)
 	}
-	defer file.Close()
+	return nil
+}
 
-	_, err = io.Copy(file, result.Body)
+func (s *S3Storage) AbortMultipartUpload(ctx context.Context, key, uploadId string) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadId),
+	})
 	if err != nil {
-		logger.AppLogger.Error("Failed to copy content from S3 to local file", zap.Error(err), zap.String("filePath", localPath))
-		return "", fmt.Errorf("failed to copy content: %v", err)
+		return s.wrapUploadError(uploadId, "failed to abort multipart upload", err)
 	}
+	return nil
+}
 
-	logger.AppLogger.Info("File downloaded successfully from S3", zap.String("bucket", bucket), zap.String("key", key), zap.String("localPath", localPath))
-	return localPath, nil
+// optionalString leaves empty values out of the request
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return aws.String(value)
+}
+
+// wrapUploadError turns the error of a multipart upload that expired or was aborted into
+// ErrUploadNotFound
+func (s *S3Storage) wrapUploadError(uploadId, message string, err error) error {
+	var noSuchUpload *types.NoSuchUpload
+	if errors.As(err, &noSuchUpload) {
+		return fmt.Errorf("%s: %w", uploadId, ErrUploadNotFound)
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+// wrapError turns the not found errors of GetObject and HeadObject into ErrObjectNotFound
+func (s *S3Storage) wrapError(key, message string, err error) error {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return fmt.Errorf("%s: %v", message, err)
 }
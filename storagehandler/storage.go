@@ -0,0 +1,126 @@
+package storagehandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+	"video_processor/appconst"
+	"video_processor/logger"
+	"video_processor/retry"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrObjectChanged is returned by GetRange when the object no longer has the expected ETag
+	ErrObjectChanged = errors.New("object changed")
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	// ChecksumCRC32C is the base64 encoded CRC32C of the whole content, empty when the
+	// backend did not store one
+	ChecksumCRC32C string
+	// Encryption is the server side encryption of the object, "aws:kms", "aws:kms:dsse",
+	// "AES256" or "SSE-C", empty when the backend does not encrypt
+	Encryption string
+}
+
+// Storage is where source videos are read from and processed output is written to.
+// Keys are slash separated whatever the backend.
+type Storage interface {
+	// Get opens the object for reading, the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange opens length bytes from offset, of the object version with the given ETag
+	GetRange(ctx context.Context, key string, offset, length int64, etag string) (io.ReadCloser, error)
+	Put(ctx context.Context, key string, body io.Reader, metadata ObjectMetadata) error
+	// List returns every object whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Delete succeeds when the object does not exist
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+}
+
+// Store is the storage used by the segmenter and the watermill handlers, replaced by main
+// with the backend chosen by STORAGE_BACKEND
+var Store Storage = NewLocalStorage(appconst.DefaultLocalStorageDir)
+
+// NewStorageFromEnv builds the backend selected by STORAGE_BACKEND, s3 by default
+func NewStorageFromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", appconst.StorageBackendS3:
+		pathStyle := false
+		if value := os.Getenv("S3_FORCE_PATH_STYLE"); value != "" {
+			var err error
+			pathStyle, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid S3_FORCE_PATH_STYLE %q: %v", value, err)
+			}
+		}
+
+		return NewS3Storage(context.TODO(), S3Config{
+			Bucket:          envOrDefault("S3_BUCKET", appconst.AWSVideoS3BuckerName),
+			Region:          envOrDefault("S3_REGION", appconst.AWSRegion),
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			UsePathStyle:    pathStyle,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	case appconst.StorageBackendLocal:
+		return NewLocalStorage(envOrDefault("STORAGE_LOCAL_DIR", appconst.DefaultLocalStorageDir)), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+func envOrDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// UploadFile stores a local file under the given key
+func UploadFile(ctx context.Context, inputFilePath, key string, metadata ObjectMetadata) error {
+	file, err := os.Open(inputFilePath)
+	if err != nil {
+		logger.AppLogger.Error("Error opening file", zap.Error(err), zap.String("filePath", inputFilePath))
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	if err := Store.Put(ctx, key, file, metadata); err != nil {
+		logger.AppLogger.Error("Error uploading file", zap.Error(err), zap.String("key", key))
+		return fmt.Errorf("error uploading file: %w", err)
+	}
+
+	logger.AppLogger.Info("File uploaded successfully", zap.String("filePath", inputFilePath), zap.String("key", key))
+	return nil
+}
+
+// GetObjectETag returns the ETag of an object, it changes whenever the object content changes
+func GetObjectETag(ctx context.Context, key string) (string, error) {
+	info, err := Store.Stat(ctx, key)
+	if err != nil {
+		logger.AppLogger.Error("Failed to stat object", zap.Error(err), zap.String("key", key))
+		return "", notFoundIsPermanent(fmt.Errorf("failed to stat object: %w", err))
+	}
+	return info.ETag, nil
+}
+
+// notFoundIsPermanent stops retries of a missing object, it does not show up by waiting
+func notFoundIsPermanent(err error) error {
+	if errors.Is(err, ErrObjectNotFound) {
+		return retry.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,194 @@
+package storagehandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"video_processor/appconst"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+// maxMultipartParts is the most parts S3 accepts for one object
+const maxMultipartParts = 10000
+
+// Uploader uploads the files of one job and records its progress, so an upload stopped by
+// a crash or a failure skips the files and parts that already made it when run again
+type Uploader struct {
+	journal *uploadJournal
+	// Tags are added to every uploaded object
+	Tags map[string]string
+}
+
+// NewUploader opens the progress of the job, e.g. the video id, left by a previous run
+func NewUploader(jobId string) (*Uploader, error) {
+	journal, err := openUploadJournal(filepath.Join(appconst.UploadStateDir, jobId+".jsonl"))
+	if err != nil {
+		logger.AppLogger.Error("Failed to open upload journal", zap.Error(err), zap.String("jobId", jobId))
+		return nil, err
+	}
+	return &Uploader{journal: journal}, nil
+}
+
+// Close keeps the recorded progress for the next run
+func (u *Uploader) Close() error {
+	return u.journal.close()
+}
+
+// Finish drops the recorded progress once every file is uploaded, or when the output is
+// thrown away. Multipart uploads that never completed are aborted first, the backend keeps
+// and bills their parts otherwise.
+func (u *Uploader) Finish(ctx context.Context) error {
+	if multipart, ok := Store.(MultipartStorage); ok {
+		for key, uploadId := range u.journal.openUploads() {
+			err := multipart.AbortMultipartUpload(ctx, key, uploadId)
+			if err != nil && !errors.Is(err, ErrUploadNotFound) {
+				// The parts stay until a lifecycle rule cleans them up, keep the journal so
+				// the next run can abort them
+				logger.AppLogger.Error("Failed to abort multipart upload", zap.Error(err), zap.String("key", key))
+				u.journal.close()
+				return err
+			}
+			logger.AppLogger.Info("Aborted multipart upload", zap.String("key", key))
+		}
+	}
+	return u.journal.remove()
+}
+
+// UploadFile uploads a local file under the given key unless a previous run already did.
+// Large files go up in parts when the backend supports it.
+func (u *Uploader) UploadFile(ctx context.Context, inputFilePath, key string) error {
+	info, err := os.Stat(inputFilePath)
+	if err != nil {
+		logger.AppLogger.Error("Error opening file", zap.Error(err), zap.String("filePath", inputFilePath))
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	size, modTime := info.Size(), info.ModTime().UnixNano()
+
+	state := u.journal.state(key)
+	if state != nil && state.Done && state.matches(size, modTime) {
+		logger.AppLogger.Debug("File already uploaded", zap.String("filePath", inputFilePath), zap.String("key", key))
+		return nil
+	}
+
+	metadata := MetadataForKey(key)
+	metadata.Tags = u.Tags
+
+	if multipart, ok := Store.(MultipartStorage); ok && size >= appconst.MultipartUploadThreshold {
+		err = u.uploadMultipart(ctx, multipart, inputFilePath, key, metadata, size, modTime, state)
+		if errors.Is(err, ErrUploadNotFound) {
+			// The upload expired or was cleaned up by a lifecycle rule, start it over
+			logger.AppLogger.Warn("Multipart upload is gone, starting over", zap.String("key", key))
+			err = u.uploadMultipart(ctx, multipart, inputFilePath, key, metadata, size, modTime, nil)
+		}
+	} else {
+		err = UploadFile(ctx, inputFilePath, key, metadata)
+	}
+	if err != nil {
+		return err
+	}
+
+	return u.journal.record(journalRecord{Key: key, Size: size, ModTime: modTime, Done: true})
+}
+
+func (u *Uploader) uploadMultipart(ctx context.Context, multipart MultipartStorage, inputFilePath, key string, metadata ObjectMetadata, size, modTime int64, state *fileState) error {
+	file, err := os.Open(inputFilePath)
+	if err != nil {
+		logger.AppLogger.Error("Error opening file", zap.Error(err), zap.String("filePath", inputFilePath))
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	if state != nil && state.UploadId != "" && !state.matches(size, modTime) {
+		// The parts belong to an older version of the file
+		if err := multipart.AbortMultipartUpload(ctx, key, state.UploadId); err != nil && !errors.Is(err, ErrUploadNotFound) {
+			logger.AppLogger.Warn("Failed to abort stale multipart upload", zap.Error(err), zap.String("key", key))
+		}
+		state = nil
+	}
+
+	uploadId := ""
+	uploaded := make(map[int32]UploadedPart)
+	if state != nil && state.UploadId != "" {
+		uploadId, uploaded = state.UploadId, state.Parts
+		logger.AppLogger.Info("Resuming multipart upload",
+			zap.String("key", key),
+			zap.Int("uploadedParts", len(uploaded)))
+	} else {
+		uploadId, err = multipart.CreateMultipartUpload(ctx, key, metadata)
+		if err != nil {
+			return err
+		}
+		if err := u.journal.record(journalRecord{Key: key, Size: size, ModTime: modTime, UploadId: uploadId}); err != nil {
+			return err
+		}
+	}
+
+	partSize := max(int64(appconst.MultipartPartSize), (size+maxMultipartParts-1)/maxMultipartParts)
+	partCount := int32((size + partSize - 1) / partSize)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var partErrors []error
+	sem := make(chan struct{}, appconst.MultipartUploadConcurrency)
+	for number := int32(1); number <= partCount; number++ {
+		if _, ok := uploaded[number]; ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(number int32) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			offset := int64(number-1) * partSize
+			section := io.NewSectionReader(file, offset, min(partSize, size-offset))
+			part, err := multipart.UploadPart(ctx, key, uploadId, number, section)
+			if err == nil {
+				err = u.journal.record(journalRecord{Key: key, Size: size, ModTime: modTime, UploadId: uploadId, Part: &part})
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				partErrors = append(partErrors, fmt.Errorf("part %d: %w", number, err))
+				return
+			}
+			uploaded[number] = part
+		}(number)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(partErrors) > 0 {
+		return errors.Join(partErrors...)
+	}
+
+	parts := make([]UploadedPart, 0, len(uploaded))
+	for _, part := range uploaded {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+
+	if err := multipart.CompleteMultipartUpload(ctx, key, uploadId, parts); err != nil {
+		return err
+	}
+
+	logger.AppLogger.Info("Multipart upload completed",
+		zap.String("filePath", inputFilePath),
+		zap.String("key", key),
+		zap.Int("parts", len(parts)))
+	return nil
+}
@@ -0,0 +1,159 @@
+package storagehandler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+// journalRecord is one line of an upload journal. A record without UploadId, Part or Done
+// never appears, every record says what happened to one file.
+type journalRecord struct {
+	Key string `json:"key"`
+	// Size and ModTime identify the local file, a regenerated file is uploaded again
+	Size     int64         `json:"size"`
+	ModTime  int64         `json:"mod_time"`
+	UploadId string        `json:"upload_id,omitempty"`
+	Part     *UploadedPart `json:"part,omitempty"`
+	Done     bool          `json:"done,omitempty"`
+}
+
+// fileState is what the journal knows about the upload of one file
+type fileState struct {
+	Size     int64
+	ModTime  int64
+	UploadId string
+	Parts    map[int32]UploadedPart
+	Done     bool
+}
+
+func (s *fileState) matches(size, modTime int64) bool {
+	return s.Size == size && s.ModTime == modTime
+}
+
+// uploadJournal persists upload progress as an append-only file of JSON lines, so recording
+// a finished segment costs one short write however many segments the video has
+type uploadJournal struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	files map[string]*fileState
+}
+
+func openUploadJournal(path string) (*uploadJournal, error) {
+	journal := &uploadJournal{path: path, files: make(map[string]*fileState)}
+
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open upload journal: %v", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			var record journalRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				// The process stopped in the middle of the last write
+				logger.AppLogger.Warn("Skipping unreadable upload journal record", zap.Error(err), zap.String("path", path))
+				continue
+			}
+			journal.apply(record)
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read upload journal: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload journal directory: %v", err)
+	}
+	journal.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload journal: %v", err)
+	}
+
+	return journal, nil
+}
+
+func (j *uploadJournal) apply(record journalRecord) {
+	state, ok := j.files[record.Key]
+	if !ok || !state.matches(record.Size, record.ModTime) || (record.UploadId != "" && record.UploadId != state.UploadId) {
+		state = &fileState{Size: record.Size, ModTime: record.ModTime, UploadId: record.UploadId, Parts: make(map[int32]UploadedPart)}
+		j.files[record.Key] = state
+	}
+	if record.Part != nil {
+		state.Parts[record.Part.Number] = *record.Part
+	}
+	if record.Done {
+		state.Done = true
+	}
+}
+
+// state returns a copy of the state of the file, nil when the journal has nothing on it
+func (j *uploadJournal) state(key string) *fileState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state, ok := j.files[key]
+	if !ok {
+		return nil
+	}
+	copied := *state
+	copied.Parts = make(map[int32]UploadedPart, len(state.Parts))
+	for number, part := range state.Parts {
+		copied.Parts[number] = part
+	}
+	return &copied
+}
+
+// openUploads returns the upload id of every multipart upload that was started and did not
+// complete, by key
+func (j *uploadJournal) openUploads() map[string]string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	uploads := make(map[string]string)
+	for key, state := range j.files {
+		if state.UploadId != "" && !state.Done {
+			uploads[key] = state.UploadId
+		}
+	}
+	return uploads
+}
+
+func (j *uploadJournal) record(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload journal record: %v", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write upload journal: %v", err)
+	}
+	j.apply(record)
+	return nil
+}
+
+func (j *uploadJournal) close() error {
+	return j.file.Close()
+}
+
+// remove deletes the journal once nothing is left to resume
+func (j *uploadJournal) remove() error {
+	j.file.Close()
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload journal: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package storagehandler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUploadJournalReplay(t *testing.T) {
+	part := func(number int32) *UploadedPart {
+		return &UploadedPart{Number: number, ETag: "etag", ChecksumCRC32C: "crc"}
+	}
+
+	tests := []struct {
+		name    string
+		records []journalRecord
+		// trailing is appended after the records, e.g. a line cut by a crash
+		trailing        string
+		key             string
+		wantState       *fileState
+		wantOpenUploads map[string]string
+	}{
+		{
+			name:            "no records",
+			key:             "a.ts",
+			wantOpenUploads: map[string]string{},
+		},
+		{
+			name: "single request upload done",
+			records: []journalRecord{
+				{Key: "a.ts", Size: 10, ModTime: 1, Done: true},
+			},
+			key:             "a.ts",
+			wantState:       &fileState{Size: 10, ModTime: 1, Parts: map[int32]UploadedPart{}, Done: true},
+			wantOpenUploads: map[string]string{},
+		},
+		{
+			name: "multipart upload in progress",
+			records: []journalRecord{
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1"},
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Part: part(1)},
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Part: part(3)},
+			},
+			key: "a.mp4",
+			wantState: &fileState{Size: 10, ModTime: 1, UploadId: "u1", Parts: map[int32]UploadedPart{
+				1: *part(1),
+				3: *part(3),
+			}},
+			wantOpenUploads: map[string]string{"a.mp4": "u1"},
+		},
+		{
+			name: "completed multipart upload is not open",
+			records: []journalRecord{
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1"},
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Part: part(1)},
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Done: true},
+			},
+			key:             "a.mp4",
+			wantState:       &fileState{Size: 10, ModTime: 1, UploadId: "u1", Parts: map[int32]UploadedPart{1: *part(1)}, Done: true},
+			wantOpenUploads: map[string]string{},
+		},
+		{
+			name: "regenerated file starts over",
+			records: []journalRecord{
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Part: part(1)},
+				{Key: "a.mp4", Size: 12, ModTime: 2, UploadId: "u2"},
+			},
+			key:             "a.mp4",
+			wantState:       &fileState{Size: 12, ModTime: 2, UploadId: "u2", Parts: map[int32]UploadedPart{}},
+			wantOpenUploads: map[string]string{"a.mp4": "u2"},
+		},
+		{
+			name: "new upload of the same file drops the old parts",
+			records: []journalRecord{
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Part: part(1)},
+				{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u2", Part: part(2)},
+			},
+			key:             "a.mp4",
+			wantState:       &fileState{Size: 10, ModTime: 1, UploadId: "u2", Parts: map[int32]UploadedPart{2: *part(2)}},
+			wantOpenUploads: map[string]string{"a.mp4": "u2"},
+		},
+		{
+			name: "line cut by a crash is skipped",
+			records: []journalRecord{
+				{Key: "a.ts", Size: 10, ModTime: 1, Done: true},
+			},
+			trailing:        `{"key":"b.ts","si`,
+			key:             "b.ts",
+			wantOpenUploads: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upload_state", "video.jsonl")
+			if len(tt.records) > 0 || tt.trailing != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				var content []byte
+				for _, record := range tt.records {
+					line, err := json.Marshal(record)
+					if err != nil {
+						t.Fatal(err)
+					}
+					content = append(append(content, line...), '\n')
+				}
+				content = append(content, tt.trailing...)
+				if err := os.WriteFile(path, content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			journal, err := openUploadJournal(path)
+			if err != nil {
+				t.Fatalf("openUploadJournal() error = %v", err)
+			}
+			defer journal.close()
+
+			if got := journal.state(tt.key); !reflect.DeepEqual(got, tt.wantState) {
+				t.Errorf("state(%q) = %+v, want %+v", tt.key, got, tt.wantState)
+			}
+			if got := journal.openUploads(); !reflect.DeepEqual(got, tt.wantOpenUploads) {
+				t.Errorf("openUploads() = %v, want %v", got, tt.wantOpenUploads)
+			}
+		})
+	}
+}
+
+func TestUploadJournalRecordSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.jsonl")
+
+	journal, err := openUploadJournal(path)
+	if err != nil {
+		t.Fatalf("openUploadJournal() error = %v", err)
+	}
+	records := []journalRecord{
+		{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1"},
+		{Key: "a.mp4", Size: 10, ModTime: 1, UploadId: "u1", Part: &UploadedPart{Number: 1, ETag: "e1"}},
+		{Key: "b.ts", Size: 5, ModTime: 1, Done: true},
+	}
+	for _, record := range records {
+		if err := journal.record(record); err != nil {
+			t.Fatalf("record() error = %v", err)
+		}
+	}
+	journal.close()
+
+	reopened, err := openUploadJournal(path)
+	if err != nil {
+		t.Fatalf("openUploadJournal() error = %v", err)
+	}
+
+	want := &fileState{Size: 10, ModTime: 1, UploadId: "u1", Parts: map[int32]UploadedPart{1: {Number: 1, ETag: "e1"}}}
+	if got := reopened.state("a.mp4"); !reflect.DeepEqual(got, want) {
+		t.Errorf("state(a.mp4) = %+v, want %+v", got, want)
+	}
+	if got := reopened.state("b.ts"); got == nil || !got.Done {
+		t.Errorf("state(b.ts) = %+v, want done", got)
+	}
+
+	if err := reopened.remove(); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("journal still exists after remove: %v", err)
+	}
+}
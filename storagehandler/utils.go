@@ -8,14 +8,22 @@ type VideoInfo struct {
 	UploadedBy string `json:"uploaded_by"`
 	CourseId   string `json:"course_id"`
 	VideoId    string `json:"video_id"`
+	RunId      string `json:"run_id"`
 	Filename   string
 }
 
+// GenerateSegmentS3Key is the prefix the output of a run is uploaded under. A reprocessed video
+// gets a new run id, so uploaded objects are never overwritten and can be cached for good.
 func GenerateSegmentS3Key(info VideoInfo) string {
-	return fmt.Sprintf("course/%s/%s/%s/video_segment/%s",
+	key := fmt.Sprintf("course/%s/%s/%s/video_segment/%s",
 		info.UploadedBy,
 		info.CourseId,
 		info.VideoId,
 		info.VideoId,
 	)
+	// Output saved before runs had an id is resumed under the prefix it started with
+	if info.RunId != "" {
+		key += "/" + info.RunId
+	}
+	return key
 }
@@ -118,3 +118,13 @@ func DeleteDirContents(dirPath string) error {
 	logger.AppLogger.Info("Successfully deleted all contents of directory", zap.String("path", dirPath))
 	return nil
 }
+
+func DeleteDir(dirPath string) error {
+	err := os.RemoveAll(dirPath)
+	if err != nil {
+		logger.AppLogger.Error("Failed to delete directory", zap.Error(err), zap.String("path", dirPath))
+		return fmt.Errorf("failed to delete directory %s: %v", dirPath, err)
+	}
+	logger.AppLogger.Info("Successfully deleted directory", zap.String("path", dirPath))
+	return nil
+}
@@ -2,12 +2,20 @@ package watermill
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"video_processor/appconst"
 	"video_processor/hlssegmenter"
+	"video_processor/jobstore"
+	"video_processor/jobtracker"
 	"video_processor/logger"
 	"video_processor/messagemodel"
+	"video_processor/retry"
+	"video_processor/storagehandler"
 
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -16,29 +24,97 @@ func HandleNewVideoUploadEvent(msg *message.Message) {
 	err := json.Unmarshal(msg.Payload, &videoInfo)
 	if err != nil {
 		logger.AppLogger.Error("cannot unmarshal message", zap.Error(err), zap.Any("msg", msg))
+		PublishToDeadLetter(appconst.TopicNewVideoUploaded, msg, "", deadLetterStageDecode, err)
+		msg.Ack()
 		return
 	}
 
 	if videoInfo.RawVidS3Key == "" {
 		logger.AppLogger.Error("s3key is empty", zap.Any("videoInfo", videoInfo))
+		err := fmt.Errorf("s3key is empty")
+		jobtracker.SetFailed(videoInfo.VideoId, err)
+		PublishToDeadLetter(appconst.TopicNewVideoUploaded, msg, videoInfo.VideoId, deadLetterStageValidate, err)
+		msg.Ack()
 		return
 	}
 
+	ctx, err := jobtracker.Start(videoInfo.VideoId)
+	if errors.Is(err, jobtracker.ErrJobRunning) {
+		logger.AppLogger.Info("duplicate message for a video being processed", zap.String("videoId", videoInfo.VideoId))
+		msg.Ack()
+		return
+	}
+	if err != nil {
+		logger.AppLogger.Info("job cancelled before processing started", zap.String("videoId", videoInfo.VideoId))
+		msg.Ack()
+		return
+	}
+
+	var sourceETag string
+	err = retry.ForStage(retry.StageS3Download).Do(ctx, videoInfo.RawVidS3Key, func() error {
+		var err error
+		sourceETag, err = storagehandler.GetObjectETag(ctx, videoInfo.RawVidS3Key)
+		return err
+	})
+	if ctx.Err() != nil {
+		msg.Ack()
+		return
+	}
+	if err != nil {
+		logger.AppLogger.Error("cannot read source object", zap.Error(err), zap.Any("S3Key", videoInfo.RawVidS3Key))
+		jobtracker.SetFailed(videoInfo.VideoId, err)
+		PublishToDeadLetter(appconst.TopicNewVideoUploaded, msg, videoInfo.VideoId, string(retry.StageS3Download), err)
+		msg.Ack()
+		return
+	}
+
+	// The same source content was already processed, keep the existing result
+	if jobstore.HasCompleted(videoInfo.VideoId, videoInfo.RawVidS3Key, sourceETag) {
+		logger.AppLogger.Info("duplicate message for an already processed video",
+			zap.String("videoId", videoInfo.VideoId),
+			zap.String("etag", sourceETag))
+		jobtracker.SetStage(videoInfo.VideoId, jobtracker.StageDone)
+		msg.Ack()
+		return
+	}
+
+	// Persisted only now, a duplicate of a processed video must leave the stored result alone
+	jobstore.SaveVideoInfo(*videoInfo)
+	jobstore.SetSourceETag(videoInfo.VideoId, sourceETag)
+	jobstore.RecordAttempt(videoInfo.VideoId)
+
 	segmentOutputDir := os.Getenv("OUTPUT_SEGMENT_DIR")
-	logcalOutputDir, err := hlssegmenter.StartSegmentProcess(videoInfo.RawVidS3Key, segmentOutputDir)
+	segmentOutput, err := hlssegmenter.StartSegmentProcess(ctx, *videoInfo, segmentOutputDir)
+
+	if ctx.Err() != nil {
+		logger.AppLogger.Info("segment process cancelled", zap.String("videoId", videoInfo.VideoId))
+		msg.Ack()
+		return
+	}
 
 	if err != nil {
 		logger.AppLogger.Error("cannot start segment process", zap.Error(err), zap.Any("S3Key", videoInfo.RawVidS3Key))
+		jobtracker.SetFailed(videoInfo.VideoId, err)
+		PublishToDeadLetter(appconst.TopicNewVideoUploaded, msg, videoInfo.VideoId, deadLetterStageSegment, err)
+		msg.Ack()
 		return
 	}
 
 	processedSegmentsInfo := messagemodel.ProcessedSegmentsInfo{
 		VideoId:        videoInfo.VideoId,
+		RunId:          uuid.NewString(),
 		CourseId:       videoInfo.CourseId,
 		UploadedBy:     videoInfo.UploadedBy,
-		LocalOutputDir: logcalOutputDir,
+		LocalOutputDir: segmentOutput.Dir,
+		MasterPlaylist: segmentOutput.MasterPlaylist,
+		DashManifest:   segmentOutput.DashManifest,
+		Thumbnails:     segmentOutput.Thumbnails,
+		Posters:        segmentOutput.Posters,
 	}
 
+	jobstore.SaveProcessedSegments(processedSegmentsInfo)
+	// The upload handler claims the run next
+	jobtracker.Handoff(videoInfo.VideoId)
 	go VideoProcessedPublisher(processedSegmentsInfo)
 	msg.Ack()
 }
@@ -0,0 +1,459 @@
+package watermill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"video_processor/logger"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	redisStreamUUIDField     = "uuid"
+	redisStreamMetadataField = "metadata"
+	redisStreamPayloadField  = "payload"
+)
+
+type RedisStreamConfig struct {
+	// ConsumerGroup is shared by every video_processor instance, so each entry is handled once
+	ConsumerGroup string
+	// Consumer identifies this instance inside the group, it must be stable across restarts
+	// so the instance picks its own pending entries back up
+	Consumer string
+	// MaxLen trims each stream to roughly this many entries, 0 keeps everything
+	MaxLen int64
+	// BlockTime is how long a single XREADGROUP call waits for new entries
+	BlockTime time.Duration
+	// ClaimInterval is how often entries left pending by other consumers are checked
+	ClaimInterval time.Duration
+	// MinIdleTime is how long an entry stays pending before another consumer takes it over.
+	// It must be longer than RefreshInterval, entries being handled are re-claimed well before.
+	MinIdleTime time.Duration
+	// RefreshInterval is how often an entry that is still being handled is re-claimed, resetting
+	// its idle time so no other consumer takes over a long running job
+	RefreshInterval time.Duration
+	// MaxInFlight is how many entries of one topic are handled at the same time
+	MaxInFlight int
+	// NackResendSleep is the delay before a nacked message is delivered again
+	NackResendSleep time.Duration
+}
+
+// RedisStreamPubSub is a watermill Publisher and Subscriber backed by Redis Streams and consumer groups.
+// Messages are delivered at least once: an entry is XACKed only after the handler Acks it.
+type RedisStreamPubSub struct {
+	client *redis.Client
+	config RedisStreamConfig
+
+	closing chan struct{}
+	closed  bool
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+}
+
+func NewRedisStreamPubSub(client *redis.Client, config RedisStreamConfig) *RedisStreamPubSub {
+	return &RedisStreamPubSub{
+		client:  client,
+		config:  config,
+		closing: make(chan struct{}),
+	}
+}
+
+func (p *RedisStreamPubSub) Publish(topic string, messages ...*message.Message) error {
+	if p.isClosed() {
+		return errors.New("redis stream pubsub is closed")
+	}
+
+	for _, msg := range messages {
+		metadata, err := json.Marshal(msg.Metadata)
+		if err != nil {
+			return fmt.Errorf("cannot marshal metadata of message %s: %w", msg.UUID, err)
+		}
+
+		args := &redis.XAddArgs{
+			Stream: topic,
+			Values: map[string]interface{}{
+				redisStreamUUIDField:     msg.UUID,
+				redisStreamMetadataField: string(metadata),
+				redisStreamPayloadField:  string(msg.Payload),
+			},
+		}
+		if p.config.MaxLen > 0 {
+			args.MaxLen = p.config.MaxLen
+			args.Approx = true
+		}
+
+		if err := p.client.XAdd(context.Background(), args).Err(); err != nil {
+			logger.AppLogger.Error("Failed to add message to redis stream",
+				zap.Error(err),
+				zap.String("topic", topic),
+				zap.String("messageID", msg.UUID))
+			return fmt.Errorf("cannot add message %s to stream %s: %w", msg.UUID, topic, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *RedisStreamPubSub) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	if p.isClosed() {
+		return nil, errors.New("redis stream pubsub is closed")
+	}
+
+	err := p.client.XGroupCreateMkStream(ctx, topic, p.config.ConsumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, fmt.Errorf("cannot create consumer group %s for stream %s: %w", p.config.ConsumerGroup, topic, err)
+	}
+
+	output := make(chan *message.Message)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(output)
+		p.consume(ctx, topic, output)
+	}()
+
+	return output, nil
+}
+
+func (p *RedisStreamPubSub) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.closing)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return nil
+}
+
+func (p *RedisStreamPubSub) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// PendingMessages returns the entries of the topic that were delivered to a consumer of the
+// group but not acked yet. They are delivered again, by this or by another instance.
+func (p *RedisStreamPubSub) PendingMessages(ctx context.Context, topic string) ([]*message.Message, error) {
+	var messages []*message.Message
+	start := "-"
+	for {
+		pending, err := p.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: topic,
+			Group:  p.config.ConsumerGroup,
+			Start:  start,
+			End:    "+",
+			Count:  100,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list pending entries of stream %s: %w", topic, err)
+		}
+		if len(pending) == 0 {
+			return messages, nil
+		}
+
+		for _, entry := range pending {
+			entries, err := p.client.XRange(ctx, topic, entry.ID, entry.ID).Result()
+			if err != nil {
+				return nil, fmt.Errorf("cannot read pending entry %s of stream %s: %w", entry.ID, topic, err)
+			}
+			// A pending entry can be trimmed from the stream already
+			for _, streamEntry := range entries {
+				msg, err := entryToMessage(streamEntry)
+				if err != nil {
+					continue
+				}
+				messages = append(messages, msg)
+			}
+		}
+		// The next page starts after the last entry, exclusive
+		start = "(" + pending[len(pending)-1].ID
+	}
+}
+
+// topicConsumer tracks the entries of one topic that are being handled
+type topicConsumer struct {
+	topic    string
+	output   chan<- *message.Message
+	slots    chan struct{}
+	inflight sync.WaitGroup
+}
+
+func (p *RedisStreamPubSub) consume(ctx context.Context, topic string, output chan<- *message.Message) {
+	maxInFlight := p.config.MaxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	c := &topicConsumer{
+		topic:  topic,
+		output: output,
+		slots:  make(chan struct{}, maxInFlight),
+	}
+	// The output channel is closed once consume returns, every delivery must be over by then
+	defer c.inflight.Wait()
+
+	// Entries this consumer read but never acked before a restart come first
+	if !p.deliverPending(ctx, c) {
+		return
+	}
+
+	lastClaim := time.Now()
+	for {
+		if time.Since(lastClaim) >= p.config.ClaimInterval {
+			if !p.claimAbandoned(ctx, c) {
+				return
+			}
+			lastClaim = time.Now()
+		}
+
+		// Wait for a free slot before reading, an entry read but not handled yet would go idle
+		if !p.acquire(ctx, c) {
+			return
+		}
+
+		streams, err := p.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    p.config.ConsumerGroup,
+			Consumer: p.config.Consumer,
+			Streams:  []string{topic, ">"},
+			Count:    1,
+			Block:    p.config.BlockTime,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			<-c.slots
+			continue
+		}
+		if err != nil {
+			<-c.slots
+			if ctx.Err() != nil {
+				return
+			}
+			logger.AppLogger.Error("Failed to read from redis stream", zap.Error(err), zap.String("topic", topic))
+			if !p.sleep(ctx, p.config.BlockTime) {
+				return
+			}
+			continue
+		}
+
+		p.dispatch(ctx, c, firstEntry(streams))
+	}
+}
+
+func (p *RedisStreamPubSub) deliverPending(ctx context.Context, c *topicConsumer) bool {
+	// Pending entries stay in the list until acked, so the cursor moves past each dispatched one
+	cursor := "0"
+	for {
+		if !p.acquire(ctx, c) {
+			return false
+		}
+
+		streams, err := p.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    p.config.ConsumerGroup,
+			Consumer: p.config.Consumer,
+			Streams:  []string{c.topic, cursor},
+			Count:    1,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			<-c.slots
+			logger.AppLogger.Error("Failed to read pending redis stream entries", zap.Error(err), zap.String("topic", c.topic))
+			return ctx.Err() == nil
+		}
+
+		entry := firstEntry(streams)
+		if entry == nil {
+			<-c.slots
+			return true
+		}
+		cursor = entry.ID
+		p.dispatch(ctx, c, entry)
+	}
+}
+
+func (p *RedisStreamPubSub) claimAbandoned(ctx context.Context, c *topicConsumer) bool {
+	cursor := "0"
+	for {
+		if !p.acquire(ctx, c) {
+			return false
+		}
+
+		entries, next, err := p.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.topic,
+			Group:    p.config.ConsumerGroup,
+			Consumer: p.config.Consumer,
+			MinIdle:  p.config.MinIdleTime,
+			Start:    cursor,
+			Count:    1,
+		}).Result()
+		if err != nil {
+			<-c.slots
+			logger.AppLogger.Error("Failed to claim abandoned redis stream entries", zap.Error(err), zap.String("topic", c.topic))
+			return ctx.Err() == nil
+		}
+
+		if len(entries) == 0 {
+			<-c.slots
+		} else {
+			logger.AppLogger.Info("Claimed abandoned redis stream entry", zap.String("topic", c.topic), zap.String("entryID", entries[0].ID))
+			p.dispatch(ctx, c, &entries[0])
+		}
+
+		// XAUTOCLAIM returns 0-0 once the whole pending list has been scanned
+		if next == "0-0" || next == "" {
+			return true
+		}
+		cursor = next
+	}
+}
+
+// acquire waits for a free delivery slot of the topic
+func (p *RedisStreamPubSub) acquire(ctx context.Context, c *topicConsumer) bool {
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-p.closing:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dispatch delivers the entry in the background and frees the slot acquired for it once done
+func (p *RedisStreamPubSub) dispatch(ctx context.Context, c *topicConsumer, entry *redis.XMessage) {
+	if entry == nil {
+		<-c.slots
+		return
+	}
+
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		defer func() { <-c.slots }()
+		p.deliver(ctx, c.topic, *entry, c.output)
+	}()
+}
+
+// deliver hands the entry to the subscriber and blocks until it is acked, resending it on nack.
+// While the handler runs the entry is re-claimed every RefreshInterval so it never looks abandoned.
+func (p *RedisStreamPubSub) deliver(ctx context.Context, topic string, entry redis.XMessage, output chan<- *message.Message) {
+	msg, err := entryToMessage(entry)
+	if err != nil {
+		// A malformed entry can never be handled, ack it so it does not block the stream
+		logger.AppLogger.Error("Dropping malformed redis stream entry", zap.Error(err), zap.String("topic", topic), zap.String("entryID", entry.ID))
+		p.client.XAck(ctx, topic, p.config.ConsumerGroup, entry.ID)
+		return
+	}
+
+	refresh := time.NewTicker(p.refreshInterval())
+	defer refresh.Stop()
+
+	for {
+		msgToSend := msg.Copy()
+		msgCtx, cancel := context.WithCancel(ctx)
+		msgToSend.SetContext(msgCtx)
+
+		select {
+		case output <- msgToSend:
+		case <-p.closing:
+			cancel()
+			return
+		case <-ctx.Done():
+			cancel()
+			return
+		}
+
+	waitForAck:
+		for {
+			select {
+			case <-msgToSend.Acked():
+				cancel()
+				if err := p.client.XAck(ctx, topic, p.config.ConsumerGroup, entry.ID).Err(); err != nil {
+					logger.AppLogger.Error("Failed to ack redis stream entry", zap.Error(err), zap.String("topic", topic), zap.String("entryID", entry.ID))
+				}
+				return
+			case <-msgToSend.Nacked():
+				cancel()
+				break waitForAck
+			case <-refresh.C:
+				p.refreshOwnership(ctx, topic, entry.ID)
+			case <-p.closing:
+				cancel()
+				return
+			case <-ctx.Done():
+				cancel()
+				return
+			}
+		}
+
+		if !p.sleep(ctx, p.config.NackResendSleep) {
+			return
+		}
+	}
+}
+
+// refreshOwnership re-claims an entry this consumer is still handling, which resets its idle time.
+// JUSTID keeps the delivery counter unchanged.
+func (p *RedisStreamPubSub) refreshOwnership(ctx context.Context, topic string, entryID string) {
+	err := p.client.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   topic,
+		Group:    p.config.ConsumerGroup,
+		Consumer: p.config.Consumer,
+		Messages: []string{entryID},
+	}).Err()
+	if err != nil {
+		logger.AppLogger.Error("Failed to refresh ownership of redis stream entry", zap.Error(err), zap.String("topic", topic), zap.String("entryID", entryID))
+	}
+}
+
+func (p *RedisStreamPubSub) refreshInterval() time.Duration {
+	if p.config.RefreshInterval > 0 {
+		return p.config.RefreshInterval
+	}
+	return p.config.MinIdleTime / 2
+}
+
+func (p *RedisStreamPubSub) sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-p.closing:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func firstEntry(streams []redis.XStream) *redis.XMessage {
+	for _, stream := range streams {
+		if len(stream.Messages) > 0 {
+			return &stream.Messages[0]
+		}
+	}
+	return nil
+}
+
+func entryToMessage(entry redis.XMessage) (*message.Message, error) {
+	uuid, _ := entry.Values[redisStreamUUIDField].(string)
+	payload, ok := entry.Values[redisStreamPayloadField].(string)
+	if !ok {
+		return nil, fmt.Errorf("entry %s has no payload", entry.ID)
+	}
+
+	msg := message.NewMessage(uuid, []byte(payload))
+	if metadata, ok := entry.Values[redisStreamMetadataField].(string); ok && metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &msg.Metadata); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal metadata of entry %s: %w", entry.ID, err)
+		}
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,161 @@
+package watermill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestEntryToMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       map[string]interface{}
+		wantUUID     string
+		wantPayload  string
+		wantMetadata map[string]string
+		wantErr      bool
+	}{
+		{
+			name: "published entry",
+			values: map[string]interface{}{
+				redisStreamUUIDField:     "m1",
+				redisStreamMetadataField: `{"trace":"t1"}`,
+				redisStreamPayloadField:  `{"video_id":"v1"}`,
+			},
+			wantUUID:     "m1",
+			wantPayload:  `{"video_id":"v1"}`,
+			wantMetadata: map[string]string{"trace": "t1"},
+		},
+		{
+			name: "entry without metadata",
+			values: map[string]interface{}{
+				redisStreamUUIDField:    "m1",
+				redisStreamPayloadField: `{}`,
+			},
+			wantUUID:     "m1",
+			wantPayload:  `{}`,
+			wantMetadata: map[string]string{},
+		},
+		{
+			name:    "entry without payload",
+			values:  map[string]interface{}{redisStreamUUIDField: "m1"},
+			wantErr: true,
+		},
+		{
+			name: "invalid metadata",
+			values: map[string]interface{}{
+				redisStreamMetadataField: `{"trace":`,
+				redisStreamPayloadField:  `{}`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := entryToMessage(redis.XMessage{ID: "1-0", Values: tt.values})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("entryToMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if msg.UUID != tt.wantUUID || string(msg.Payload) != tt.wantPayload {
+				t.Errorf("entryToMessage() = %s %s, want %s %s", msg.UUID, msg.Payload, tt.wantUUID, tt.wantPayload)
+			}
+			if len(msg.Metadata) != len(tt.wantMetadata) {
+				t.Errorf("metadata = %v, want %v", msg.Metadata, tt.wantMetadata)
+			}
+			for key, value := range tt.wantMetadata {
+				if msg.Metadata.Get(key) != value {
+					t.Errorf("metadata %s = %q, want %q", key, msg.Metadata.Get(key), value)
+				}
+			}
+		})
+	}
+}
+
+func TestFirstEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		streams []redis.XStream
+		want    string
+	}{
+		{name: "no streams"},
+		{name: "empty stream", streams: []redis.XStream{{Stream: "a"}}},
+		{
+			name: "first entry of the first stream with entries",
+			streams: []redis.XStream{
+				{Stream: "a"},
+				{Stream: "b", Messages: []redis.XMessage{{ID: "2-0"}, {ID: "3-0"}}},
+			},
+			want: "2-0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := firstEntry(tt.streams)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("firstEntry() = %s, want none", got.ID)
+				}
+				return
+			}
+			if got == nil || got.ID != tt.want {
+				t.Errorf("firstEntry() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RedisStreamConfig
+		want   time.Duration
+	}{
+		{name: "configured", config: RedisStreamConfig{RefreshInterval: time.Minute, MinIdleTime: 10 * time.Minute}, want: time.Minute},
+		{name: "half the idle time", config: RedisStreamConfig{MinIdleTime: 10 * time.Minute}, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewRedisStreamPubSub(nil, tt.config)
+			if got := p.refreshInterval(); got != tt.want {
+				t.Errorf("refreshInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcquireLimitsInFlightEntries(t *testing.T) {
+	p := NewRedisStreamPubSub(nil, RedisStreamConfig{MaxInFlight: 2})
+	c := &topicConsumer{topic: "t", slots: make(chan struct{}, 2)}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if !p.acquire(ctx, c) {
+			t.Fatalf("acquire() %d failed with a free slot", i)
+		}
+	}
+
+	// Every slot is taken, acquire waits until the consumer is cancelled
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if p.acquire(cancelled, c) {
+		t.Fatal("acquire() succeeded with every slot taken")
+	}
+
+	<-c.slots
+	if !p.acquire(ctx, c) {
+		t.Fatal("acquire() failed after a slot was freed")
+	}
+
+	close(p.closing)
+	if p.acquire(ctx, c) {
+		t.Fatal("acquire() succeeded after the pubsub was closed")
+	}
+}
@@ -0,0 +1,92 @@
+package watermill
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"video_processor/appconst"
+	"video_processor/jobstore"
+	"video_processor/jobtracker"
+	"video_processor/logger"
+
+	"go.uber.org/zap"
+)
+
+// ResumeUnfinishedJobs re-enqueues every job the job store still has open, e.g. after a crash
+func ResumeUnfinishedJobs() {
+	jobs, err := jobstore.UnfinishedJobs()
+	if err != nil {
+		logger.AppLogger.Error("Cannot resume unfinished jobs", zap.Error(err))
+		return
+	}
+
+	// Entries still pending in the stream are delivered again anyway, publishing them a second
+	// time would process the video twice
+	pendingUploads := pendingVideoIds(videoProcessedTopic)
+	pendingJobs := pendingVideoIds(appconst.TopicNewVideoUploaded)
+
+	for _, job := range jobs {
+		videoInfo := job.VideoInfo
+		if videoInfo.RawVidS3Key == "" {
+			logger.AppLogger.Warn("Skipping unfinished job without s3key",
+				zap.String("videoId", videoInfo.VideoId),
+				zap.String("stage", job.Stage))
+			continue
+		}
+
+		// The output is still on disk, only the upload has to be finished
+		if job.Stage == string(jobtracker.StageUploading) && job.ProcessedSegments != nil {
+			if _, err := os.Stat(job.ProcessedSegments.LocalOutputDir); err == nil {
+				if pendingUploads[videoInfo.VideoId] {
+					logger.AppLogger.Info("Unfinished upload is still pending in the stream", zap.String("videoId", videoInfo.VideoId))
+					continue
+				}
+				logger.AppLogger.Info("Resuming unfinished upload",
+					zap.String("videoId", videoInfo.VideoId),
+					zap.String("outputDir", job.ProcessedSegments.LocalOutputDir))
+				VideoProcessedPublisher(*job.ProcessedSegments)
+				continue
+			}
+		}
+
+		if pendingJobs[videoInfo.VideoId] {
+			logger.AppLogger.Info("Unfinished job is still pending in the stream", zap.String("videoId", videoInfo.VideoId))
+			continue
+		}
+
+		logger.AppLogger.Info("Resuming unfinished job",
+			zap.String("videoId", videoInfo.VideoId),
+			zap.String("stage", job.Stage),
+			zap.Int("attempts", job.Attempts))
+
+		jobtracker.SetStage(videoInfo.VideoId, jobtracker.StageQueued)
+		PublishVideoUploadedEvent(&videoInfo)
+	}
+}
+
+// pendingVideoIds lists the videos with a message of the topic that is delivered but not acked
+// yet. Only the redis streams transport keeps such messages across restarts.
+func pendingVideoIds(topic string) map[string]bool {
+	videoIds := make(map[string]bool)
+
+	pubSub, ok := Publisher.(*RedisStreamPubSub)
+	if !ok {
+		return videoIds
+	}
+
+	messages, err := pubSub.PendingMessages(context.Background(), topic)
+	if err != nil {
+		logger.AppLogger.Error("Cannot list pending messages, resuming every unfinished job", zap.Error(err), zap.String("topic", topic))
+		return videoIds
+	}
+
+	for _, msg := range messages {
+		var payload struct {
+			VideoId string `json:"video_id"`
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err == nil && payload.VideoId != "" {
+			videoIds[payload.VideoId] = true
+		}
+	}
+	return videoIds
+}
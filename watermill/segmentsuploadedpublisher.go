@@ -0,0 +1,26 @@
+package watermill
+
+import (
+	"encoding/json"
+	"video_processor/appconst"
+	"video_processor/logger"
+	"video_processor/messagemodel"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"go.uber.org/zap"
+)
+
+// PublishSegmentsUploadedEvent tells the other services where the output of a video landed
+func PublishSegmentsUploadedEvent(uploadedInfo messagemodel.SegmentsUploadedInfo) {
+	data, err := json.Marshal(uploadedInfo)
+	if err != nil {
+		logger.AppLogger.Error("cannot marshal", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), data)
+	if err := Publisher.Publish(appconst.TopicVideoSegmentsUploaded, msg); err != nil {
+		logger.AppLogger.Error("Failed to publish video_segments_uploaded event", zap.Error(err), zap.String("videoId", uploadedInfo.VideoId))
+	}
+}
@@ -9,16 +9,17 @@ import (
 	"go.uber.org/zap"
 )
 
+// SubscribeToTopics subscribes synchronously, so anything published after it returns
+// reaches a handler, and then dispatches messages in the background
 func SubscribeToTopics() {
 	subscriber := Publisher
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx := context.Background()
 
 	// Create channels for each topic
-	videoProcessedChan, err := subscriber.Subscribe(ctx, appconst.TopicVideoProcessed)
+	videoProcessedChan, err := subscriber.Subscribe(ctx, videoProcessedTopic)
 	if err != nil {
-		logger.AppLogger.Fatal(fmt.Sprintf("Failed to subscribe to %s topic", appconst.TopicVideoProcessed), zap.Error(err))
+		logger.AppLogger.Fatal(fmt.Sprintf("Failed to subscribe to %s topic", videoProcessedTopic), zap.Error(err))
 	}
 
 	newVideoUploadedChan, err := subscriber.Subscribe(ctx, appconst.TopicNewVideoUploaded)
@@ -26,14 +27,16 @@ func SubscribeToTopics() {
 		logger.AppLogger.Fatal(fmt.Sprintf("Failed to subscribe to %s topic", appconst.TopicNewVideoUploaded), zap.Error(err))
 	}
 
-	for {
-		select {
-		case msg := <-videoProcessedChan:
-			go HandleVideoProcessedVideoEvent(msg)
-		case msg := <-newVideoUploadedChan:
-			go HandleNewVideoUploadEvent(msg)
-		case <-ctx.Done():
-			return
+	go func() {
+		for {
+			select {
+			case msg := <-videoProcessedChan:
+				go HandleVideoProcessedVideoEvent(msg)
+			case msg := <-newVideoUploadedChan:
+				go HandleNewVideoUploadEvent(msg)
+			case <-ctx.Done():
+				return
+			}
 		}
-	}
+	}()
 }
@@ -1,10 +1,21 @@
 package watermill
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 	"video_processor/appconst"
+	"video_processor/jobstore"
+	"video_processor/jobtracker"
 	"video_processor/logger"
 	"video_processor/messagemodel"
+	"video_processor/retry"
 	"video_processor/storagehandler"
 	"video_processor/utils"
 
@@ -22,45 +33,174 @@ func HandleVideoProcessedVideoEvent(msg *message.Message) {
 				string(msg.Payload)),
 			zap.Error(err),
 		)
+		PublishToDeadLetter(videoProcessedTopic, msg, "", deadLetterStageDecode, err)
 		msg.Ack()
 		return
 	}
 
 	outputDir := proccessedSegmentsInfo.LocalOutputDir
 
+	videoId := proccessedSegmentsInfo.VideoId
+	// Two uploaders of the same video would share the upload journal
+	ctx, err := jobtracker.Start(videoId)
+	if errors.Is(err, jobtracker.ErrJobRunning) {
+		logger.AppLogger.Info("duplicate message for a video being uploaded", zap.String("videoId", videoId))
+		msg.Ack()
+		return
+	}
+	if err != nil {
+		logger.AppLogger.Info("job cancelled before the upload started", zap.String("videoId", videoId))
+		msg.Ack()
+		return
+	}
+	jobtracker.SetStage(videoId, jobtracker.StageUploading)
+
 	filePaths, err := utils.GetFilePaths(outputDir)
 	if err != nil {
 		logger.AppLogger.Error("Failed to get file paths", zap.Error(err), zap.String("outputDir", outputDir))
+		jobtracker.SetFailed(videoId, err)
+		PublishToDeadLetter(videoProcessedTopic, msg, videoId, deadLetterStageUpload, err)
 		msg.Ack()
 		return
 	}
 
+	uploader, err := storagehandler.NewUploader(videoId)
+	if err != nil {
+		jobtracker.SetFailed(videoId, err)
+		PublishToDeadLetter(videoProcessedTopic, msg, videoId, deadLetterStageUpload, err)
+		msg.Ack()
+		return
+	}
+	uploader.Tags = objectTags(*proccessedSegmentsInfo)
+
+	// Files keep their place relative to the output directory, so the relative URIs of the
+	// playlists resolve under the prefix
+	keyPrefix := storagehandler.GenerateSegmentS3Key(storagehandler.VideoInfo{
+		UploadedBy: proccessedSegmentsInfo.UploadedBy,
+		CourseId:   proccessedSegmentsInfo.CourseId,
+		VideoId:    proccessedSegmentsInfo.VideoId,
+		RunId:      proccessedSegmentsInfo.RunId,
+	})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var uploadErrors []error
+	uploadPolicy := retry.ForStage(retry.StageS3Upload)
 	sem := make(chan struct{}, appconst.MaxConcurrentS3Push)
 	for _, path := range filePaths {
+		wg.Add(1)
 		go func(path string) {
+			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
-			storagehandler.GenerateSegmentS3Key(storagehandler.VideoInfo{
-				UploadedBy: proccessedSegmentsInfo.UploadedBy,
-				CourseId:   proccessedSegmentsInfo.CourseId,
-				VideoId:    proccessedSegmentsInfo.VideoId,
-			})
 
-			err := storagehandler.UploadFileToS3(path, appconst.AWSVideoS3BuckerName)
+			// Uploads still waiting for a slot are skipped once the job is cancelled
+			if ctx.Err() != nil {
+				return
+			}
+
+			relPath, err := filepath.Rel(outputDir, path)
 			if err != nil {
-				logger.AppLogger.Error("Failed to upload file to S3",
+				mu.Lock()
+				uploadErrors = append(uploadErrors, fmt.Errorf("%s: %w", path, err))
+				mu.Unlock()
+				return
+			}
+			key := segmentKey(keyPrefix, relPath)
+
+			err = uploadPolicy.Do(ctx, key, func() error {
+				return uploader.UploadFile(ctx, path, key)
+			})
+			if err != nil && ctx.Err() == nil {
+				logger.AppLogger.Error("Failed to upload file",
 					zap.Error(err),
 					zap.String("path", path),
-					zap.String("bucket", appconst.AWSVideoS3BuckerName))
-			} else {
-				logger.AppLogger.Info("File uploaded to S3 successfully",
-					zap.String("path", path),
-					zap.String("bucket", appconst.AWSVideoS3BuckerName))
+					zap.String("key", key))
+				mu.Lock()
+				uploadErrors = append(uploadErrors, fmt.Errorf("%s: %w", path, err))
+				mu.Unlock()
+			} else if err == nil {
+				logger.AppLogger.Info("File uploaded successfully", zap.String("path", path), zap.String("key", key))
 			}
 		}(path)
 	}
 
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		logger.AppLogger.Info("Upload cancelled, removing local output", zap.String("videoId", videoId), zap.String("outputDir", outputDir))
+		// The job context is cancelled already, the aborts must still go through
+		uploader.Finish(context.Background())
+		utils.DeleteDir(outputDir)
+	} else if len(uploadErrors) > 0 {
+		// The files that made it are skipped when the upload is retried
+		uploader.Close()
+		err := fmt.Errorf("%d of %d files failed to upload: %w", len(uploadErrors), len(filePaths), errors.Join(uploadErrors...))
+		jobtracker.SetFailed(videoId, err)
+		PublishToDeadLetter(videoProcessedTopic, msg, videoId, deadLetterStageUpload, err)
+	} else {
+		uploader.Finish(ctx)
+		uploadedInfo := segmentsUploadedInfo(*proccessedSegmentsInfo, keyPrefix)
+		jobstore.MarkCompleted(videoId, uploadedInfo)
+		jobtracker.SetStage(videoId, jobtracker.StageDone)
+		PublishSegmentsUploadedEvent(uploadedInfo)
+	}
+
 	// Mark the message as processed
 	msg.Ack()
 	logger.AppLogger.Info("Message processed and acknowledged", zap.String("messageID", msg.UUID))
 }
+
+// segmentKey is the object key of a file of the output directory
+func segmentKey(keyPrefix, relPath string) string {
+	return path.Join(keyPrefix, filepath.ToSlash(relPath))
+}
+
+func segmentsUploadedInfo(processedInfo messagemodel.ProcessedSegmentsInfo, keyPrefix string) messagemodel.SegmentsUploadedInfo {
+	uploadedInfo := messagemodel.SegmentsUploadedInfo{
+		UploadedBy:        processedInfo.UploadedBy,
+		CourseId:          processedInfo.CourseId,
+		VideoId:           processedInfo.VideoId,
+		KeyPrefix:         keyPrefix,
+		MasterPlaylistKey: segmentKey(keyPrefix, processedInfo.MasterPlaylist),
+	}
+	if processedInfo.DashManifest != "" {
+		uploadedInfo.DashManifestKey = segmentKey(keyPrefix, processedInfo.DashManifest)
+	}
+	if processedInfo.Thumbnails != "" {
+		uploadedInfo.ThumbnailsKey = segmentKey(keyPrefix, processedInfo.Thumbnails)
+	}
+	for _, poster := range processedInfo.Posters {
+		poster.Key = segmentKey(keyPrefix, poster.Key)
+		uploadedInfo.Posters = append(uploadedInfo.Posters, poster)
+	}
+	return uploadedInfo
+}
+
+// objectTags tags the uploaded objects with the fields named in UPLOAD_OBJECT_TAGS,
+// e.g. "course_id,video_id". No tags are set when it is empty.
+func objectTags(processedInfo messagemodel.ProcessedSegmentsInfo) map[string]string {
+	names := os.Getenv("UPLOAD_OBJECT_TAGS")
+	if names == "" {
+		return nil
+	}
+
+	fields := map[string]string{
+		"course_id":   processedInfo.CourseId,
+		"video_id":    processedInfo.VideoId,
+		"uploaded_by": processedInfo.UploadedBy,
+	}
+	tags := make(map[string]string)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		value, ok := fields[name]
+		if !ok {
+			logger.AppLogger.Warn("Unknown object tag", zap.String("tag", name))
+			continue
+		}
+		if value != "" {
+			tags[name] = value
+		}
+	}
+	return tags
+}
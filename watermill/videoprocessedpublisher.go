@@ -2,7 +2,6 @@ package watermill
 
 import (
 	"encoding/json"
-	"video_processor/appconst"
 	"video_processor/logger"
 	"video_processor/messagemodel"
 
@@ -19,7 +18,7 @@ func VideoProcessedPublisher(segmentsInfo messagemodel.ProcessedSegmentsInfo) {
 	}
 
 	msg := message.NewMessage(watermill.NewUUID(), data)
-	if err := Publisher.Publish(appconst.TopicVideoProcessed, msg); err != nil {
+	if err := Publisher.Publish(videoProcessedTopic, msg); err != nil {
 		logger.AppLogger.Error("Failed to publish video_processed event", zap.Error(err))
 	}
 }
@@ -0,0 +1,67 @@
+package watermill
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+	"video_processor/appconst"
+	"video_processor/logger"
+	"video_processor/messagemodel"
+	"video_processor/retry"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"go.uber.org/zap"
+)
+
+// Stages reported for failures that are not covered by a retry policy
+const (
+	deadLetterStageDecode   = "decode"
+	deadLetterStageValidate = "validate"
+	deadLetterStageSegment  = "segment"
+	deadLetterStageUpload   = "upload"
+)
+
+// PublishToDeadLetter records a message that cannot be processed on the dead-letter topic
+func PublishToDeadLetter(originalTopic string, msg *message.Message, videoId string, stage string, err error) {
+	attempts := 1
+	var exhausted *retry.ExhaustedError
+	if errors.As(err, &exhausted) {
+		attempts = len(exhausted.Attempts)
+		stage = string(exhausted.Stage)
+	}
+
+	failedJobInfo := messagemodel.FailedJobInfo{
+		VideoId:       videoId,
+		Stage:         stage,
+		Attempts:      attempts,
+		ErrorChain:    retry.ErrorChain(err),
+		OriginalTopic: originalTopic,
+		OriginalMsgId: msg.UUID,
+		FailedAt:      time.Now().Unix(),
+	}
+	if json.Valid(msg.Payload) {
+		failedJobInfo.OriginalPayload = json.RawMessage(msg.Payload)
+	} else {
+		// Keep unparsable payloads inspectable as a JSON string
+		failedJobInfo.OriginalPayload, _ = json.Marshal(string(msg.Payload))
+	}
+
+	data, marshalErr := json.Marshal(failedJobInfo)
+	if marshalErr != nil {
+		logger.AppLogger.Error("cannot marshal", zap.Error(marshalErr))
+		return
+	}
+
+	deadLetterMsg := message.NewMessage(watermill.NewUUID(), data)
+	if publishErr := Publisher.Publish(appconst.TopicVideoProcessingFailed, deadLetterMsg); publishErr != nil {
+		logger.AppLogger.Error("Failed to publish video_processing_failed event", zap.Error(publishErr), zap.String("videoId", videoId))
+		return
+	}
+
+	logger.AppLogger.Warn("Message moved to dead-letter topic",
+		zap.String("videoId", videoId),
+		zap.String("originalTopic", originalTopic),
+		zap.String("stage", stage),
+		zap.Int("attempts", attempts))
+}
@@ -1,12 +1,28 @@
 package watermill
 
 import (
+	"video_processor/appconst"
+	"video_processor/logger"
+
 	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
 )
 
+// PubSub is the transport every topic of the service goes through
+type PubSub interface {
+	message.Publisher
+	message.Subscriber
+}
+
 // Global publisher
-var Publisher *gochannel.GoChannel
+var Publisher PubSub
+
+// videoProcessedTopic carries the local output directory of a finished encode, so it must only
+// reach the instance that produced it
+var videoProcessedTopic = appconst.TopicVideoProcessed
 
 func init() {
 	Publisher = gochannel.NewGoChannel(
@@ -14,3 +30,14 @@ func init() {
 		watermill.NewStdLogger(false, false),
 	)
 }
+
+// UseRedisStreams swaps the in-process transport for Redis Streams so several instances
+// share the topics. It must be called before SubscribeToTopics.
+func UseRedisStreams(client *redis.Client, config RedisStreamConfig) {
+	Publisher = NewRedisStreamPubSub(client, config)
+	// The encoded files and the upload journal live on this instance's disk, give it a stream of its own
+	videoProcessedTopic = appconst.TopicVideoProcessed + "." + config.Consumer
+	logger.AppLogger.Info("Using redis streams transport",
+		zap.String("consumerGroup", config.ConsumerGroup),
+		zap.String("consumer", config.Consumer))
+}